@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrChannelRequired app.logs.v1日志缺少channel
+	ErrChannelRequired = errors.New("log channel required")
+)
+
+// Channel 输出到固定channel的日志对象，可以附加默认字段并设置独立的日志级别
+type Channel struct {
+	logger *logrus.Logger
+	name   string
+	fields logrus.Fields
+	// 由With派生的Channel共享日志级别，0表示未设置，否则为级别+1
+	level  *uint32
+	buffer *RequestBuffer
+}
+
+// NewChannel 创建输出到指定channel的日志对象，l通常由NewLogger创建
+func NewChannel(l *logrus.Logger, name string) *Channel {
+	return newChannel(l, name, nil)
+}
+
+func newChannel(l *logrus.Logger, name string, fields logrus.Fields) *Channel {
+	fs := make(logrus.Fields, len(fields)+1)
+	for k, v := range fields {
+		fs[k] = v
+	}
+	fs[ChannelKey] = name
+
+	return &Channel{logger: l, name: name, fields: fs, level: new(uint32)}
+}
+
+// Name channel名称
+func (c *Channel) Name() string {
+	return c.name
+}
+
+// Logger 底层的logrus日志对象
+func (c *Channel) Logger() *logrus.Logger {
+	return c.logger
+}
+
+// SetLevel 设置channel的日志级别，只能在底层logrus日志对象的级别之上进一步过滤，
+// 如logrus为debug级别时可以将channel设置为warning以屏蔽该channel的info日志
+func (c *Channel) SetLevel(level logrus.Level) {
+	atomic.StoreUint32(c.level, uint32(level)+1)
+}
+
+// GetLevel channel的日志级别，未设置时为底层logrus日志对象的级别
+func (c *Channel) GetLevel() logrus.Level {
+	if l := atomic.LoadUint32(c.level); l > 0 {
+		return logrus.Level(l - 1)
+	}
+
+	return c.logger.GetLevel()
+}
+
+// IsLevelEnabled 是否输出该级别的日志
+func (c *Channel) IsLevelEnabled(level logrus.Level) bool {
+	if l := atomic.LoadUint32(c.level); l > 0 && logrus.Level(l-1) < level {
+		return false
+	}
+
+	return c.logger.IsLevelEnabled(level)
+}
+
+// With 返回附加了默认字段的日志对象，kvs为交替出现的key与value，日志级别与原对象共享
+func (c *Channel) With(kvs ...interface{}) *Channel {
+	fs := kvFields(c.fields, kvs)
+	fs[ChannelKey] = c.name
+
+	return &Channel{logger: c.logger, name: c.name, fields: fs, level: c.level, buffer: c.buffer}
+}
+
+// WithFields 返回附加了默认字段的日志对象，日志级别与原对象共享
+func (c *Channel) WithFields(fields logrus.Fields) *Channel {
+	fs := make(logrus.Fields, len(c.fields)+len(fields))
+	for k, v := range c.fields {
+		fs[k] = v
+	}
+	for k, v := range fields {
+		fs[k] = v
+	}
+	fs[ChannelKey] = c.name
+
+	return &Channel{logger: c.logger, name: c.name, fields: fs, level: c.level, buffer: c.buffer}
+}
+
+// WithContext 返回使用ctx中RequestBuffer的日志对象，日志级别与原对象共享
+func (c *Channel) WithContext(ctx context.Context) *Channel {
+	return &Channel{logger: c.logger, name: c.name, fields: c.fields, level: c.level, buffer: RequestBufferFromContext(ctx)}
+}
+
+// Debugw 记录debug级别的日志
+func (c *Channel) Debugw(msg string, kvs ...interface{}) {
+	c.Logw(logrus.DebugLevel, msg, kvs...)
+}
+
+// Infow 记录info级别的日志
+func (c *Channel) Infow(msg string, kvs ...interface{}) {
+	c.Logw(logrus.InfoLevel, msg, kvs...)
+}
+
+// Warnw 记录warning级别的日志
+func (c *Channel) Warnw(msg string, kvs ...interface{}) {
+	c.Logw(logrus.WarnLevel, msg, kvs...)
+}
+
+// Errorw 记录error级别的日志
+func (c *Channel) Errorw(msg string, kvs ...interface{}) {
+	c.Logw(logrus.ErrorLevel, msg, kvs...)
+}
+
+// Logw 记录指定级别的日志，日志级别未开启且不被缓存时不处理kvs
+func (c *Channel) Logw(level logrus.Level, msg string, kvs ...interface{}) {
+	if !c.IsLevelEnabled(level) && !c.buffer.holds(level) {
+		return
+	}
+
+	fs := kvFields(c.fields, kvs)
+	fs[ChannelKey] = c.name
+	logw(c.logger, c.buffer, fs, level, msg)
+}
+
+// channel 日志条目的channel，缺少时使用DefaultChannel，要求channel时返回ErrChannelRequired
+func (af *APPLogsV1Formatter) channel(entry *logrus.Entry) (string, error) {
+	if c, _ := entry.Data[ChannelKey].(string); c != "" {
+		return c, nil
+	}
+	if af.DefaultChannel != "" {
+		return af.DefaultChannel, nil
+	}
+	if af.RequireChannel {
+		return "", errors.Wrapf(ErrChannelRequired, "log %q", entry.Message)
+	}
+
+	return "", nil
+}
@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func TestChannel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, _ := NewLogger(APPLogsV1, WithOutput(buf), WithLevel(logrus.DebugLevel))
+
+	payment := Sugar(l).With("trace_id", "t1").Channel("payment").With("order_id", 1001)
+	if payment.Name() != "payment" || payment.GetLevel() != logrus.DebugLevel {
+		t.Fatalf("Channel() name and level, Expected=%q %q, Actual=%q %q", "payment", logrus.DebugLevel, payment.Name(), payment.GetLevel())
+	}
+
+	payment.Debugw("debug", ChannelKey, "other")
+	data := buf.Bytes()
+	cases := []struct {
+		path     []interface{}
+		expected string
+	}{
+		{path: []interface{}{"channel"}, expected: "payment"},
+		{path: []interface{}{"level"}, expected: "debug"},
+		{path: []interface{}{"ctx", "trace_id"}, expected: "t1"},
+		{path: []interface{}{"ctx", "order_id"}, expected: "1001"},
+	}
+	for _, c := range cases {
+		if actual := jsoniter.Get(data, c.path...).ToString(); actual != c.expected {
+			t.Fatalf("Debugw() output %v, Expected=%q, Actual=%q", c.path, c.expected, actual)
+		}
+	}
+
+	// 派生的Channel共享日志级别
+	root := NewChannel(l, "payment")
+	derived := root.WithFields(logrus.Fields{"k": "v"})
+	root.SetLevel(logrus.WarnLevel)
+	buf.Reset()
+	derived.Infow("filtered")
+	if buf.Len() != 0 {
+		t.Fatalf("Infow() of warning channel, Expected=%q, Actual=%q", "", buf.String())
+	}
+	if derived.GetLevel() != logrus.WarnLevel {
+		t.Fatalf("GetLevel() of derived channel, Expected=%q, Actual=%q", logrus.WarnLevel, derived.GetLevel())
+	}
+	derived.Errorw("failed")
+	if actual := jsoniter.Get(buf.Bytes(), "ctx", "k").ToString(); actual != "v" {
+		t.Fatalf("Errorw() output ctx.k, Expected=%q, Actual=%q", "v", actual)
+	}
+
+	// channel的级别不能低于logrus日志对象的级别
+	l.SetLevel(logrus.ErrorLevel)
+	root.SetLevel(logrus.DebugLevel)
+	if root.IsLevelEnabled(logrus.WarnLevel) {
+		t.Fatal("IsLevelEnabled() of warning, Expected=false, Actual=true")
+	}
+}
+
+func TestFormatterChannel(t *testing.T) {
+	entry := &logrus.Entry{
+		Level:   logrus.InfoLevel,
+		Time:    time.Date(2019, 8, 12, 10, 13, 48, 0, time.UTC),
+		Message: "hello",
+		Data:    logrus.Fields{"k": "v"},
+	}
+
+	for _, enc := range []Encoding{EncodingJSON, EncodingLogfmt} {
+		f, _ := NewFormatter(APPLogsV1, WithEncoding(enc), WithDefaultChannel("default"), WithChannelRequired())
+		data, err := f.Format(entry)
+		if err != nil {
+			t.Fatalf("%s Format() error, Expected=nil, Actual=%q", enc, err.Error())
+		}
+		expected := map[Encoding]string{
+			EncodingJSON:   `{"schema":"app.logs.v1","channel":"default","level":"info","time":"2019-08-12T10:13:48Z","msg":"hello","ctx":{"k":"v"}}` + "\n",
+			EncodingLogfmt: `schema=app.logs.v1 channel=default level=info time=2019-08-12T10:13:48Z msg=hello ctx.k=v` + "\n",
+		}[enc]
+		if string(data) != expected {
+			t.Fatalf("%s Format() output, Expected=%q, Actual=%q", enc, expected, data)
+		}
+	}
+
+	f, _ := NewFormatter(APPLogsV1, WithChannelRequired())
+	if _, err := f.Format(entry); errors.Cause(err) != ErrChannelRequired {
+		t.Fatalf("Format() without channel error, Expected=%q, Actual=%v", ErrChannelRequired, err)
+	}
+	entry.Data = logrus.Fields{ChannelKey: "payment"}
+	if _, err := f.Format(entry); err != nil {
+		t.Fatalf("Format() with channel error, Expected=nil, Actual=%q", err.Error())
+	}
+}
@@ -0,0 +1,172 @@
+package logger
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Cloud Logging 可识别的特殊字段
+const (
+	cloudLoggingTraceKey          = "logging.googleapis.com/trace"
+	cloudLoggingSpanIDKey         = "logging.googleapis.com/spanId"
+	cloudLoggingSourceLocationKey = "logging.googleapis.com/sourceLocation"
+)
+
+// cloudLoggingFields 在日志内容的字段payload前后附加Cloud Logging特殊字段，payload原样保留为jsonPayload
+func cloudLoggingFields(entry *logrus.Entry, data logData, payload []field, project string) []field {
+	fs := make([]field, 0, len(payload)+5)
+	fs = append(fs, field{"severity", cloudLoggingSeverity(entry.Level)})
+	fs = append(fs, payload...)
+
+	var traceID, spanID string
+	switch d := data.(type) {
+	case *APPLogsV1Data:
+		traceID, spanID = stringValue(d.Context[TraceIDKey]), stringValue(d.Context[SpanIDKey])
+	case *HTTPRequestV1Data:
+		traceID, spanID = stringValue(d.Extra[TraceIDKey]), stringValue(d.Extra[SpanIDKey])
+		if req, ok := entry.Data[HTTPRequestReqKey].(*http.Request); ok {
+			fs = append(fs, field{"httpRequest", cloudLoggingHTTPRequest(entry, req, d)})
+			if traceID == "" {
+				traceID, spanID = parseTraceHeader(req.Header)
+			}
+		}
+	}
+
+	if traceID != "" {
+		if project != "" {
+			traceID = "projects/" + project + "/traces/" + traceID
+		}
+		fs = append(fs, field{cloudLoggingTraceKey, traceID})
+	}
+	if spanID != "" {
+		fs = append(fs, field{cloudLoggingSpanIDKey, spanID})
+	}
+	if entry.HasCaller() {
+		fs = append(fs, field{cloudLoggingSourceLocationKey, []field{
+			{"file", entry.Caller.File},
+			{"line", strconv.Itoa(entry.Caller.Line)},
+			{"function", entry.Caller.Function},
+		}})
+	}
+
+	return fs
+}
+
+// cloudLoggingSeverity logrus日志级别对应的Cloud Logging severity
+func cloudLoggingSeverity(level logrus.Level) string {
+	switch level {
+	case logrus.TraceLevel, logrus.DebugLevel:
+		return "DEBUG"
+	case logrus.InfoLevel:
+		return "INFO"
+	case logrus.WarnLevel:
+		return "WARNING"
+	case logrus.ErrorLevel:
+		return "ERROR"
+	case logrus.FatalLevel:
+		return "CRITICAL"
+	case logrus.PanicLevel:
+		return "ALERT"
+	}
+
+	return "DEFAULT"
+}
+
+// cloudLoggingHTTPRequest 映射为Cloud Logging的HttpRequest对象
+// status与latency分别取自extra中的"status"与日志条目中的"latency"(time.Duration)
+func cloudLoggingHTTPRequest(entry *logrus.Entry, req *http.Request, d *HTTPRequestV1Data) []field {
+	fs := []field{
+		{"requestMethod", d.Method},
+		{"requestUrl", req.URL.String()},
+	}
+	if d.IP != "" {
+		fs = append(fs, field{"remoteIp", d.IP})
+	}
+	if ua := req.UserAgent(); ua != "" {
+		fs = append(fs, field{"userAgent", ua})
+	}
+	if ref := req.Referer(); ref != "" {
+		fs = append(fs, field{"referer", ref})
+	}
+	if req.Proto != "" {
+		fs = append(fs, field{"protocol", req.Proto})
+	}
+	if status, ok := intValue(d.Extra[HTTPRequestStatusKey]); ok {
+		fs = append(fs, field{"status", status})
+	}
+	if latency, ok := entry.Data[HTTPRequestLatencyKey].(time.Duration); ok {
+		fs = append(fs, field{"latency", strconv.FormatFloat(latency.Seconds(), 'f', -1, 64) + "s"})
+	}
+
+	return fs
+}
+
+// parseTraceHeader 从X-Cloud-Trace-Context或W3C traceparent请求头中解析trace id与span id(16位十六进制)
+func parseTraceHeader(h http.Header) (string, string) {
+	// X-Cloud-Trace-Context: TRACE_ID/SPAN_ID;o=TRACE_TRUE，SPAN_ID为十进制
+	if v := h.Get("X-Cloud-Trace-Context"); v != "" {
+		v = strings.SplitN(v, ";", 2)[0]
+		parts := strings.SplitN(v, "/", 2)
+		if len(parts) == 1 {
+			return parts[0], ""
+		}
+		span, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return parts[0], ""
+		}
+		return parts[0], fmt.Sprintf("%016x", span)
+	}
+
+	// traceparent: VERSION-TRACE_ID-SPAN_ID-FLAGS
+	if v := h.Get("Traceparent"); v != "" {
+		parts := strings.Split(v, "-")
+		if len(parts) == 4 && len(parts[1]) == 32 && len(parts[2]) == 16 {
+			return parts[1], parts[2]
+		}
+	}
+
+	return "", ""
+}
+
+func stringValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	return ""
+}
+
+func intValue(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,120 @@
+package logger
+
+import (
+	"net/http"
+	"net/url"
+	"runtime"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+func TestCloudLoggingEncoding(t *testing.T) {
+	t.Run("APPLogsV1", func(t *testing.T) {
+		f, _ := NewFormatter(APPLogsV1,
+			WithEncoding(EncodingCloudLogging),
+			WithCloudLoggingProject("proj"),
+		)
+		l := logrus.New()
+		l.SetReportCaller(true)
+		data, err := f.Format(&logrus.Entry{
+			Logger:  l,
+			Level:   logrus.WarnLevel,
+			Time:    time.Now(),
+			Message: "hello",
+			Caller:  &runtime.Frame{Function: "main.main", File: "main.go", Line: 10},
+			Data: logrus.Fields{
+				ChannelKey: "payment",
+				TraceIDKey: "abc",
+				SpanIDKey:  "0000000000000001",
+			},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		cases := []struct {
+			path     []interface{}
+			expected string
+		}{
+			{path: []interface{}{"severity"}, expected: "WARNING"},
+			{path: []interface{}{"schema"}, expected: string(APPLogsV1)},
+			{path: []interface{}{"msg"}, expected: "hello"},
+			{path: []interface{}{"ctx", TraceIDKey}, expected: "abc"},
+			{path: []interface{}{cloudLoggingTraceKey}, expected: "projects/proj/traces/abc"},
+			{path: []interface{}{cloudLoggingSpanIDKey}, expected: "0000000000000001"},
+			{path: []interface{}{cloudLoggingSourceLocationKey, "function"}, expected: "main.main"},
+			{path: []interface{}{cloudLoggingSourceLocationKey, "line"}, expected: "10"},
+		}
+		for _, c := range cases {
+			if v := jsoniter.Get(data, c.path...).ToString(); v != c.expected {
+				t.Fatalf(`Format() output %q, Expected=%q, Actual=%q`, c.path, c.expected, v)
+			}
+		}
+	})
+
+	t.Run("HTTPRequestV1", func(t *testing.T) {
+		f := &HTTPRequestV1Formatter{TimeLayout: time.RFC3339, Encoding: EncodingCloudLogging}
+		headers := http.Header{}
+		headers.Set("User-Agent", "curl")
+		headers.Set("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000/255;o=1")
+		data, err := f.Format(&logrus.Entry{
+			Level: logrus.ErrorLevel,
+			Time:  time.Now(),
+			Data: logrus.Fields{
+				HTTPRequestReqKey: &http.Request{
+					RemoteAddr: "1.2.3.4:1234",
+					Header:     headers,
+					Method:     http.MethodGet,
+					Proto:      "HTTP/1.1",
+					URL:        &url.URL{Path: "/api", RawQuery: "foo=bar"},
+				},
+				HTTPRequestStatusKey:  500,
+				HTTPRequestLatencyKey: 1500 * time.Millisecond,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		cases := []struct {
+			path     []interface{}
+			expected string
+		}{
+			{path: []interface{}{"severity"}, expected: "ERROR"},
+			{path: []interface{}{"httpRequest", "requestMethod"}, expected: "GET"},
+			{path: []interface{}{"httpRequest", "requestUrl"}, expected: "/api?foo=bar"},
+			{path: []interface{}{"httpRequest", "remoteIp"}, expected: "1.2.3.4"},
+			{path: []interface{}{"httpRequest", "userAgent"}, expected: "curl"},
+			{path: []interface{}{"httpRequest", "protocol"}, expected: "HTTP/1.1"},
+			{path: []interface{}{"httpRequest", "status"}, expected: "500"},
+			{path: []interface{}{"httpRequest", "latency"}, expected: "1.5s"},
+			{path: []interface{}{cloudLoggingTraceKey}, expected: "105445aa7843bc8bf206b12000100000"},
+			{path: []interface{}{cloudLoggingSpanIDKey}, expected: "00000000000000ff"},
+		}
+		for _, c := range cases {
+			if v := jsoniter.Get(data, c.path...).ToString(); v != c.expected {
+				t.Fatalf(`Format() output %q, Expected=%q, Actual=%q`, c.path, c.expected, v)
+			}
+		}
+	})
+}
+
+func TestCloudLoggingSeverity(t *testing.T) {
+	cases := map[logrus.Level]string{
+		logrus.TraceLevel: "DEBUG",
+		logrus.DebugLevel: "DEBUG",
+		logrus.InfoLevel:  "INFO",
+		logrus.WarnLevel:  "WARNING",
+		logrus.ErrorLevel: "ERROR",
+		logrus.FatalLevel: "CRITICAL",
+		logrus.PanicLevel: "ALERT",
+	}
+	for level, expected := range cases {
+		if v := cloudLoggingSeverity(level); v != expected {
+			t.Fatalf("cloudLoggingSeverity(%q), Expected=%q, Actual=%q", level, expected, v)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	consoleTimeLayout = "15:04:05.000"
+	// 消息部分的最小宽度，使后续的上下文字段尽量对齐
+	consoleMessageWidth = 40
+
+	colorRed    = 31
+	colorYellow = 33
+	colorBlue   = 36
+	colorGray   = 37
+)
+
+var (
+	_ logrus.Formatter = (*ConsoleFormatter)(nil)
+)
+
+// ConsoleFormatter 将app.logs.v1与http.request.v1日志渲染为便于阅读的彩色文本，适用于本地开发
+// 日志条目中包含"request"字段时按http.request.v1渲染，否则按app.logs.v1渲染
+type ConsoleFormatter struct {
+	// 时间格式，默认"15:04:05.000"
+	TimeLayout string
+	// 强制输出颜色
+	ForceColors bool
+	// 禁用颜色，输出不是终端时会自动禁用
+	DisableColors bool
+}
+
+// Format implements logrus.Formatter interface
+func (cf *ConsoleFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	timeLayout := cf.TimeLayout
+	if timeLayout == "" {
+		timeLayout = consoleTimeLayout
+	}
+
+	cw := &consoleWriter{
+		buf:   &bytes.Buffer{},
+		color: levelColor(entry.Level),
+	}
+	if cf.ForceColors || (!cf.DisableColors && entry.Logger != nil && isTerminal(entry.Logger.Out)) {
+		cw.colored = true
+	}
+
+	if _, ok := entry.Data[HTTPRequestReqKey]; ok {
+		data, err := (&HTTPRequestV1Formatter{TimeLayout: timeLayout}).data(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		cw.writeHeader(data.Time, entry.Level, "http", data.Method+" "+data.Path)
+		cw.writeField("ip", data.IP)
+		if data.User != "" {
+			cw.writeField(HTTPRequestUserKey, data.User)
+		}
+		headers := logrus.Fields{}
+		for k, v := range data.Headers {
+			headers[k] = v
+		}
+		cw.writeFields("headers.", headers)
+		cw.writeFields("get.", data.Get)
+		cw.writeFields("post.", data.Post)
+		cw.writeFields("", data.Extra)
+		httpRequestV1Pool.Put(data)
+	} else {
+		data := (&APPLogsV1Formatter{TimeLayout: timeLayout}).data(entry)
+		cw.writeHeader(data.Time, entry.Level, data.Channel, data.Message)
+		cw.writeFields("", data.Context)
+		appLogsV1Pool.Put(data)
+	}
+
+	cw.writeTraces()
+	cw.buf.WriteByte('\n')
+	return cw.buf.Bytes(), nil
+}
+
+type consoleTrace struct {
+	key   string
+	msg   string
+	trace []string
+}
+
+type consoleWriter struct {
+	buf     *bytes.Buffer
+	colored bool
+	color   int
+	traces  []consoleTrace
+}
+
+func (cw *consoleWriter) writeColored(color int, s string) {
+	if cw.colored {
+		fmt.Fprintf(cw.buf, "\x1b[%dm%s\x1b[0m", color, s)
+		return
+	}
+	cw.buf.WriteString(s)
+}
+
+func (cw *consoleWriter) writeHeader(t string, level logrus.Level, channel, message string) {
+	cw.buf.WriteString(t)
+	cw.buf.WriteByte(' ')
+	cw.writeColored(cw.color, fmt.Sprintf("%-5s", levelText(level)))
+	cw.buf.WriteByte(' ')
+	if channel != "" {
+		cw.writeColored(colorGray, "["+channel+"]")
+		cw.buf.WriteByte(' ')
+	}
+	fmt.Fprintf(cw.buf, "%-*s", consoleMessageWidth, message)
+}
+
+// writeFields 按key排序输出字段，错误信息的调用栈会在行尾单独输出
+func (cw *consoleWriter) writeFields(prefix string, fields logrus.Fields) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := fields[k]
+		if msg, trace, ok := errInfo(v); ok {
+			cw.writeField(prefix+k, msg)
+			if len(trace) > 0 {
+				cw.traces = append(cw.traces, consoleTrace{key: prefix + k, msg: msg, trace: trace})
+			}
+			continue
+		}
+		cw.writeField(prefix+k, v)
+	}
+}
+
+func (cw *consoleWriter) writeField(key string, value interface{}) {
+	cw.buf.WriteByte(' ')
+	cw.writeColored(cw.color, key)
+	cw.buf.WriteByte('=')
+	cw.buf.WriteString(consoleValue(value))
+}
+
+// writeTraces 以多行形式输出错误信息的调用栈
+func (cw *consoleWriter) writeTraces() {
+	for _, t := range cw.traces {
+		cw.buf.WriteString("\n    ")
+		cw.writeColored(colorRed, t.key+": "+t.msg)
+		for _, frame := range t.trace {
+			fn, file := frame, ""
+			if i := strings.IndexByte(frame, ' '); i >= 0 {
+				fn, file = frame[:i], frame[i+1:]
+			}
+			cw.buf.WriteString("\n        ")
+			cw.buf.WriteString(fn)
+			if file != "" {
+				cw.buf.WriteString("\n            ")
+				cw.writeColored(colorGray, file)
+			}
+		}
+	}
+}
+
+// errInfo 判断v是否为makeErrInfo生成的错误信息
+func errInfo(v interface{}) (string, []string, bool) {
+	info, ok := v.(logrus.Fields)
+	if !ok || len(info) != 2 {
+		return "", nil, false
+	}
+
+	msg, ok := info["msg"].(string)
+	if !ok {
+		return "", nil, false
+	}
+	trace, ok := info["trace"].([]string)
+	if !ok {
+		return "", nil, false
+	}
+
+	return msg, trace, true
+}
+
+func consoleValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "<nil>"
+	case string:
+		return consoleString(v)
+	case encodeFailure:
+		return consoleString(encodePlaceholder(v.typ, v.reason))
+	case fmt.Stringer:
+		return consoleString(v.String())
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	}
+
+	if s, err := jsoniter.MarshalToString(v); err == nil {
+		return s
+	}
+	return consoleString(fmt.Sprintf("%+v", v))
+}
+
+// consoleString 字符串为空或包含空白、引号、等号及不可打印字符时加上引号
+func consoleString(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r == '"' || r == '=' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+
+	return s
+}
+
+func levelText(level logrus.Level) string {
+	if level == logrus.WarnLevel {
+		return "WARN"
+	}
+	return strings.ToUpper(level.String())
+}
+
+func levelColor(level logrus.Level) int {
+	switch level {
+	case logrus.TraceLevel, logrus.DebugLevel:
+		return colorGray
+	case logrus.WarnLevel:
+		return colorYellow
+	case logrus.ErrorLevel, logrus.FatalLevel, logrus.PanicLevel:
+		return colorRed
+	default:
+		return colorBlue
+	}
+}
+
+// isTerminal 判断输出是否为终端
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
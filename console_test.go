@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func TestConsoleFormatter(t *testing.T) {
+	now := time.Date(2019, 8, 12, 10, 13, 48, 0, time.UTC)
+
+	t.Run("APPLogsV1", func(t *testing.T) {
+		f := &ConsoleFormatter{DisableColors: true}
+		data, err := f.Format(&logrus.Entry{
+			Level:   logrus.WarnLevel,
+			Time:    now,
+			Message: "hello world",
+			Data: logrus.Fields{
+				ChannelKey:      "payment",
+				"foo":           "bar baz",
+				"n":             1,
+				logrus.ErrorKey: errors.New("wow"),
+			},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		expected := `10:13:48.000 WARN  [payment] hello world                              error=wow foo="bar baz" n=1`
+		if lines[0] != expected {
+			t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, lines[0])
+		}
+		if len(lines) < 3 || lines[1] != "    error: wow" {
+			t.Fatalf("Format() stack trace output, Actual=%q", lines[1:])
+		}
+		if strings.Contains(string(data), "\x1b[") {
+			t.Fatalf("Format() output, Expected no colors, Actual=%q", data)
+		}
+	})
+
+	t.Run("HTTPRequestV1", func(t *testing.T) {
+		f := &ConsoleFormatter{ForceColors: true}
+		data, err := f.Format(&logrus.Entry{
+			Level: logrus.InfoLevel,
+			Time:  now,
+			Data: logrus.Fields{
+				HTTPRequestReqKey: &http.Request{
+					RemoteAddr: "1.2.3.4:1234",
+					Method:     http.MethodGet,
+					URL:        &url.URL{Path: "/api", RawQuery: "foo=bar"},
+				},
+				"status": 200,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		for _, s := range []string{"GET /api", "\x1b[36mINFO \x1b[0m", "=1.2.3.4", "get.foo\x1b[0m=bar", "status\x1b[0m=200"} {
+			if !strings.Contains(string(data), s) {
+				t.Fatalf("Format() output, Expected contains %q, Actual=%q", s, data)
+			}
+		}
+	})
+}
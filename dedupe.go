@@ -0,0 +1,133 @@
+package logger
+
+import (
+	"io"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// RepeatedCountKey 重复日志汇总中窗口内被抑制的重复条数，输出在ctx中
+	RepeatedCountKey = "repeated_count"
+	// FirstSeenKey 重复日志汇总中第一次出现的时间，输出在ctx中
+	FirstSeenKey = "first_seen"
+	// LastSeenKey 重复日志汇总中最后一次出现的时间，输出在ctx中
+	LastSeenKey = "last_seen"
+
+	// DefaultDedupeWindow 默认的重复日志检测窗口
+	DefaultDedupeWindow = 10 * time.Second
+)
+
+var _ logrus.Formatter = (*DedupeFormatter)(nil)
+
+// Dedupe 重复日志抑制规则，(level, channel, msg, error信息)相同的日志视为重复
+type Dedupe struct {
+	// 检测窗口，从第一次出现开始计算，默认DefaultDedupeWindow
+	Window time.Duration
+}
+
+func (d Dedupe) window() time.Duration {
+	if d.Window <= 0 {
+		return DefaultDedupeWindow
+	}
+
+	return d.Window
+}
+
+type dedupeKey struct {
+	level   logrus.Level
+	channel string
+	msg     string
+	err     string
+}
+
+type dedupeCounter struct {
+	key      dedupeKey
+	first    time.Time
+	last     time.Time
+	repeated int
+	data     logrus.Fields
+}
+
+func (c *dedupeCounter) started() time.Time {
+	return c.first
+}
+
+// summary 窗口内有重复时返回汇总日志，时间为最后一次出现的时间
+func (c *dedupeCounter) summary(time.Duration) *logrus.Entry {
+	if c.repeated == 0 {
+		return nil
+	}
+
+	data := make(logrus.Fields, len(c.data)+3)
+	for k, v := range c.data {
+		data[k] = v
+	}
+	data[RepeatedCountKey] = c.repeated
+	data[FirstSeenKey] = c.first
+	data[LastSeenKey] = c.last
+
+	return &logrus.Entry{
+		Data:    data,
+		Time:    c.last,
+		Level:   c.key.level,
+		Message: c.key.msg,
+	}
+}
+
+// DedupeFormatter 抑制重复日志的格式化对象，窗口内第一次出现的日志正常输出，之后重复的日志不会被格式化与输出。
+// 窗口结束后，有重复的日志会在之后的第一条日志之前输出一条汇总日志，ctx为最后一次出现时的ctx，
+// 并附加repeated_count、first_seen、last_seen，之后没有日志时可以通过FlushSummaries定时输出；
+// 汇总日志格式化失败时只丢弃汇总日志；logrus的hook在格式化之前执行，被抑制的日志仍会触发hook
+type DedupeFormatter struct {
+	Formatter logrus.Formatter
+	Dedupe    Dedupe
+
+	counters windowCounters
+}
+
+// NewDedupeFormatter 创建抑制f的重复输出的格式化对象，可通过logger.SetFormatter包装已有的日志对象
+func NewDedupeFormatter(f logrus.Formatter, d Dedupe) *DedupeFormatter {
+	return &DedupeFormatter{
+		Formatter: f,
+		Dedupe:    d,
+	}
+}
+
+// Format implements logrus.Formatter interface
+func (df *DedupeFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	key := dedupeKey{level: entry.Level, msg: entry.Message}
+	key.channel, _ = entry.Data[ChannelKey].(string)
+	if err, ok := entry.Data[logrus.ErrorKey].(error); ok && err != nil {
+		key.err = err.Error()
+	}
+
+	var repeated bool
+	summaries := df.counters.update(key, entry.Time, df.Dedupe.window(), func(wc windowCounter) windowCounter {
+		c, ok := wc.(*dedupeCounter)
+		if !ok {
+			return &dedupeCounter{key: key, first: entry.Time, last: entry.Time}
+		}
+		repeated = true
+		c.repeated++
+		c.last = entry.Time
+		c.data = entry.Data
+		return c
+	})
+
+	output := formatSummaries(df.Formatter, summaries)
+	if repeated {
+		return output, nil
+	}
+
+	b, err := df.Formatter.Format(entry)
+	if err != nil || len(output) == 0 {
+		return b, err
+	}
+	return append(output, b...), nil
+}
+
+func (df *DedupeFormatter) flushSummaries(w io.Writer, now time.Time, all bool) error {
+	return df.counters.flush(df.Formatter, w, now, df.Dedupe.window(), all)
+}
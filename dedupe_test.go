@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestDedupeFormatter(t *testing.T) {
+	now := time.Date(2019, 8, 12, 10, 13, 48, 0, time.UTC)
+	f, _ := NewFormatter(APPLogsV1, WithSortedKeys())
+	df := NewDedupeFormatter(f, Dedupe{Window: 10 * time.Second})
+
+	var output []string
+	format := formatLines(t, df, &output)
+
+	for i := 0; i < 4; i++ {
+		format(&logrus.Entry{
+			Level:   logrus.ErrorLevel,
+			Time:    now.Add(time.Duration(i) * time.Second),
+			Message: "call failed",
+			Data:    logrus.Fields{ChannelKey: "payment", logrus.ErrorKey: errors.New("timeout"), "i": i},
+		})
+	}
+	// error信息不同，不是重复日志
+	format(&logrus.Entry{
+		Level:   logrus.ErrorLevel,
+		Time:    now.Add(5 * time.Second),
+		Message: "call failed",
+		Data:    logrus.Fields{ChannelKey: "payment", logrus.ErrorKey: errors.New("refused")},
+	})
+	format(&logrus.Entry{Level: logrus.InfoLevel, Time: now.Add(12 * time.Second), Message: "recovered", Data: logrus.Fields{}})
+
+	expected := []string{
+		`{"schema":"app.logs.v1","channel":"payment","level":"error","time":"2019-08-12T10:13:48Z","msg":"call failed","ctx":{"error":{"msg":"timeout","trace":[]},"i":0}}`,
+		`{"schema":"app.logs.v1","channel":"payment","level":"error","time":"2019-08-12T10:13:53Z","msg":"call failed","ctx":{"error":{"msg":"refused","trace":[]}}}`,
+		`{"schema":"app.logs.v1","channel":"payment","level":"error","time":"2019-08-12T10:13:51Z","msg":"call failed",` +
+			`"ctx":{"error":{"msg":"timeout","trace":[]},"first_seen":"2019-08-12T10:13:48Z","i":3,"last_seen":"2019-08-12T10:13:51Z","repeated_count":3}}`,
+		`{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:14:00Z","msg":"recovered"}`,
+	}
+	if strings.Join(output, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, output)
+	}
+
+	// 通过NewLogger抑制重复日志
+	if _, _, n := loggerLines(t, WithDedupe(Dedupe{Window: time.Hour})); n != 1 {
+		t.Fatalf("NewLogger() with dedupe lines, Expected=1, Actual=%d", n)
+	}
+}
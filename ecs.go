@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ECSVersion 输出的Elastic Common Schema版本
+	ECSVersion = "1.6.0"
+	// DefaultECSNamespace ctx、extra等自定义字段默认所在的命名空间
+	DefaultECSNamespace = "custom"
+)
+
+// ecsFields 将日志内容映射为ECS字段
+// 规范中的公共字段映射到对应的ECS字段，ctx、extra、headers、get、post保持原有名称放在自定义命名空间下，
+// @timestamp固定使用RFC3339Nano格式，不受TimeLayout影响，_encode_errors与截断记录同其他编码方式一样输出在顶层
+func ecsFields(entry *logrus.Entry, data logData, ns string) []field {
+	if ns == "" {
+		ns = DefaultECSNamespace
+	}
+	ts := entry.Time.Format(time.RFC3339Nano)
+
+	switch d := data.(type) {
+	case *APPLogsV1Data:
+		fs := ecsBaseFields(d.Schema, ts, d.Level, d.Channel, d.Service, d.Environment, d.Metadata)
+		fs = append(fs, field{"message", d.Message})
+
+		ctx, errFields := ecsError(d.Context)
+		if errFields != nil {
+			fs = append(fs, field{"error", errFields})
+		}
+		if len(ctx) > 0 {
+			fs = append(fs, field{ns, []field{{"ctx", ctx}}})
+		}
+		fs = encodeErrorFields(fs, d.encodeErrors)
+		return truncationFields(fs, d.truncations, d.collapsed)
+	case *HTTPRequestV1Data:
+		fs := ecsBaseFields(d.Schema, ts, d.Level, "", d.Service, d.Environment, d.Metadata)
+		fs = append(fs,
+			field{"http", []field{{"request", []field{{"method", d.Method}}}}},
+			field{"url", []field{{"path", d.Path}}},
+			field{"source", []field{{"ip", d.IP}}},
+		)
+		if d.User != "" {
+			fs = append(fs, field{"user", []field{{"id", d.User}}})
+		}
+		if ua, ok := d.Headers["User-Agent"]; ok {
+			fs = append(fs, field{"user_agent", []field{{"original", ua}}})
+		}
+
+		extra, errFields := ecsError(d.Extra)
+		if errFields != nil {
+			fs = append(fs, field{"error", errFields})
+		}
+		custom := make([]field, 0, 4)
+		if len(d.Headers) > 0 {
+			custom = append(custom, field{"headers", d.Headers})
+		}
+		if len(d.Get) > 0 {
+			custom = append(custom, field{"get", d.Get})
+		}
+		if len(d.Post) > 0 {
+			custom = append(custom, field{"post", d.Post})
+		}
+		if len(extra) > 0 {
+			custom = append(custom, field{"extra", extra})
+		}
+		if len(custom) > 0 {
+			fs = append(fs, field{ns, custom})
+		}
+		fs = encodeErrorFields(fs, d.encodeErrors)
+		return truncationFields(fs, d.truncations, d.collapsed)
+	}
+
+	return data.fields()
+}
+
+// ecsBaseFields 两种规范共有的字段，channel映射为log.logger
+func ecsBaseFields(schema, t, level, channel, service, env string, meta *Metadata) []field {
+	log := []field{{"level", level}}
+	if channel != "" {
+		log = append(log, field{"logger", channel})
+	}
+
+	fs := []field{
+		{"@timestamp", t},
+		{"log", log},
+		{"ecs", []field{{"version", ECSVersion}}},
+		{"event", []field{{"dataset", schema}}},
+	}
+
+	svc := make([]field, 0, 3)
+	if service != "" {
+		svc = append(svc, field{"name", service})
+	}
+	if env != "" {
+		svc = append(svc, field{"environment", env})
+	}
+	if meta != nil && meta.Version != "" {
+		svc = append(svc, field{"version", meta.Version})
+	}
+	if len(svc) > 0 {
+		fs = append(fs, field{"service", svc})
+	}
+
+	if meta == nil {
+		return fs
+	}
+	if meta.Host != "" {
+		fs = append(fs, field{"host", []field{{"hostname", meta.Host}}})
+	}
+	if meta.PID != 0 {
+		fs = append(fs, field{"process", []field{{"pid", meta.PID}}})
+	}
+	k8s := make([]field, 0, 3)
+	if meta.Pod != "" {
+		k8s = append(k8s, field{"pod", []field{{"name", meta.Pod}}})
+	}
+	if meta.Namespace != "" {
+		k8s = append(k8s, field{"namespace", meta.Namespace})
+	}
+	if meta.Node != "" {
+		k8s = append(k8s, field{"node", []field{{"name", meta.Node}}})
+	}
+	if len(k8s) > 0 {
+		fs = append(fs, field{"kubernetes", k8s})
+	}
+
+	return fs
+}
+
+// ecsError 取出logrus.ErrorKey对应的错误信息映射为ECS error字段，返回去掉该错误后的上下文
+func ecsError(ctx logrus.Fields) (logrus.Fields, []field) {
+	msg, trace, ok := errInfo(ctx[logrus.ErrorKey])
+	if !ok {
+		return ctx, nil
+	}
+
+	rest := make(logrus.Fields, len(ctx)-1)
+	for k, v := range ctx {
+		if k != logrus.ErrorKey {
+			rest[k] = v
+		}
+	}
+
+	errFields := []field{{"message", msg}}
+	if len(trace) > 0 {
+		errFields = append(errFields, field{"stack_trace", strings.Join(trace, "\n")})
+	}
+
+	return rest, errFields
+}
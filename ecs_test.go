@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func TestECSEncoding(t *testing.T) {
+	now := time.Date(2019, 8, 12, 10, 13, 48, 0, time.UTC)
+	meta := &Metadata{Host: "h1", PID: 42, Version: "v1", Pod: "p1"}
+
+	t.Run("APPLogsV1", func(t *testing.T) {
+		f := &APPLogsV1Formatter{
+			TimeLayout: time.RFC3339,
+			Service:    "svc",
+			Metadata:   meta,
+			Encoding:   EncodingECS,
+		}
+		data, err := f.Format(&logrus.Entry{
+			Level:   logrus.ErrorLevel,
+			Time:    now,
+			Message: "hello",
+			Data: logrus.Fields{
+				ChannelKey:      "payment",
+				"foo":           "bar",
+				logrus.ErrorKey: errors.New("boom"),
+			},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		cases := []struct {
+			path     []interface{}
+			expected string
+		}{
+			{path: []interface{}{"@timestamp"}, expected: "2019-08-12T10:13:48Z"},
+			{path: []interface{}{"log", "level"}, expected: "error"},
+			{path: []interface{}{"log", "logger"}, expected: "payment"},
+			{path: []interface{}{"ecs", "version"}, expected: ECSVersion},
+			{path: []interface{}{"event", "dataset"}, expected: string(APPLogsV1)},
+			{path: []interface{}{"service", "name"}, expected: "svc"},
+			{path: []interface{}{"service", "version"}, expected: "v1"},
+			{path: []interface{}{"host", "hostname"}, expected: "h1"},
+			{path: []interface{}{"process", "pid"}, expected: "42"},
+			{path: []interface{}{"kubernetes", "pod", "name"}, expected: "p1"},
+			{path: []interface{}{"message"}, expected: "hello"},
+			{path: []interface{}{"error", "message"}, expected: "boom"},
+			{path: []interface{}{DefaultECSNamespace, "ctx", "foo"}, expected: "bar"},
+			{path: []interface{}{DefaultECSNamespace, "ctx", logrus.ErrorKey}, expected: ""},
+		}
+		for _, c := range cases {
+			if v := jsoniter.Get(data, c.path...).ToString(); v != c.expected {
+				t.Fatalf(`Format() output %q, Expected=%q, Actual=%q`, c.path, c.expected, v)
+			}
+		}
+		if v := jsoniter.Get(data, "error", "stack_trace").ToString(); v == "" {
+			t.Fatal(`Format() output "error.stack_trace", Expected not empty`)
+		}
+	})
+
+	t.Run("HTTPRequestV1", func(t *testing.T) {
+		f := &HTTPRequestV1Formatter{
+			TimeLayout:   "2006-01-02 15:04:05",
+			Encoding:     EncodingECS,
+			ECSNamespace: "app",
+		}
+		headers := http.Header{}
+		headers.Set("User-Agent", "curl")
+		data, err := f.Format(&logrus.Entry{
+			Level: logrus.InfoLevel,
+			Time:  now.Add(123 * time.Millisecond),
+			Data: logrus.Fields{
+				HTTPRequestReqKey: &http.Request{
+					RemoteAddr: "1.2.3.4:1234",
+					Header:     headers,
+					Method:     http.MethodGet,
+					URL:        &url.URL{Path: "/api", RawQuery: "foo=bar"},
+				},
+				HTTPRequestUserKey: 7,
+				"status":           200,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		cases := []struct {
+			path     []interface{}
+			expected string
+		}{
+			// @timestamp不受TimeLayout影响
+			{path: []interface{}{"@timestamp"}, expected: "2019-08-12T10:13:48.123Z"},
+			{path: []interface{}{"event", "dataset"}, expected: string(HTTPRequestV1)},
+			{path: []interface{}{"http", "request", "method"}, expected: "GET"},
+			{path: []interface{}{"url", "path"}, expected: "/api"},
+			{path: []interface{}{"source", "ip"}, expected: "1.2.3.4"},
+			{path: []interface{}{"user", "id"}, expected: "7"},
+			{path: []interface{}{"user_agent", "original"}, expected: "curl"},
+			{path: []interface{}{"app", "get", "foo"}, expected: "bar"},
+			{path: []interface{}{"app", "extra", "status"}, expected: "200"},
+		}
+		for _, c := range cases {
+			if v := jsoniter.Get(data, c.path...).ToString(); v != c.expected {
+				t.Fatalf(`Format() output %q, Expected=%q, Actual=%q`, c.path, c.expected, v)
+			}
+		}
+	})
+}
@@ -0,0 +1,355 @@
+package logger
+
+import (
+	"strconv"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Encoding 日志编码方式
+type Encoding string
+
+const (
+	// EncodingJSON json编码，默认编码方式
+	EncodingJSON Encoding = "json"
+	// EncodingLogfmt logfmt编码，嵌套字段以"."连接为多个key输出
+	EncodingLogfmt Encoding = "logfmt"
+	// EncodingECS 映射为Elastic Common Schema字段后以json编码
+	EncodingECS Encoding = "ecs"
+	// EncodingCloudLogging 在json编码的基础上附加Google Cloud Logging可识别的特殊字段
+	EncodingCloudLogging Encoding = "cloud-logging"
+	// EncodingOTLP 每行输出一个只包含一条LogRecord的OTLP/JSON ExportLogsServiceRequest
+	EncodingOTLP Encoding = "otlp"
+)
+
+var (
+	// ErrEncodingNotFound 不支持的日志编码方式
+	ErrEncodingNotFound = errors.New("log encoding not found")
+)
+
+// validEncoding 是否为支持的编码方式，为空时使用json编码
+func validEncoding(enc Encoding) bool {
+	switch enc {
+	case "", EncodingJSON, EncodingLogfmt, EncodingECS, EncodingCloudLogging, EncodingOTLP:
+		return true
+	}
+
+	return false
+}
+
+// field 有序输出的日志字段
+type field struct {
+	Key   string
+	Value interface{}
+}
+
+// logData 从日志条目中提取的规范化日志内容
+type logData interface {
+	// fields 按输出顺序返回字段
+	fields() []field
+	// release 使用完毕后放回对象池
+	release()
+}
+
+// extractor 可从日志条目中提取规范化日志内容的格式化对象
+type extractor interface {
+	extract(entry *logrus.Entry) (logData, error)
+}
+
+// encodeOptions 编码时使用的格式化对象配置
+type encodeOptions struct {
+	encoding            Encoding
+	ecsNamespace        string
+	cloudLoggingProject string
+	keyMap              map[string]string
+	// 是否输出Unix时间戳，此时无法直接使用jsoniter编码日志内容
+	epoch bool
+	// json编码时是否按key排序嵌套的map
+	sortKeys bool
+	// 是否将容器字段展开为"."连接的顶层字段，以及展开的层数
+	flatten      bool
+	flattenDepth int
+	// 是否限制了日志内容的大小，此时可能需要输出截断记录
+	limits bool
+}
+
+// direct 是否可以直接使用jsoniter编码日志内容
+func (o encodeOptions) direct() bool {
+	return len(o.keyMap) == 0 && !o.epoch && !o.flatten && !o.limits
+}
+
+// streaming 是否可以使用jsonEncoder直接输出json，TypeStable需要修改日志内容，由调用方另行判断
+func (o encodeOptions) streaming() bool {
+	return (o.encoding == "" || o.encoding == EncodingJSON) && o.direct()
+}
+
+// json 编码使用的jsoniter配置
+func (o encodeOptions) json() jsoniter.API {
+	if o.sortKeys {
+		return sortedJSON
+	}
+	return jsoniter.ConfigDefault
+}
+
+// fields 返回按keyMap重命名、按需展开容器字段后的顶层字段，出现重名字段时返回错误
+// 展开得到的key与其他顶层字段冲突时，保留顶层字段，展开得到的值依次使用"_2"、"_3"...后缀
+func (o encodeOptions) fields(data logData) ([]field, error) {
+	fs := data.fields()
+	if len(o.keyMap) == 0 && !o.flatten {
+		return fs, nil
+	}
+
+	used := make(map[string]bool, len(fs))
+	for _, f := range fs {
+		if !o.flatten || !flattenContainers[f.Key] {
+			used[o.mapKey(f.Key)] = true
+		}
+	}
+
+	out := make([]field, 0, len(fs))
+	for _, f := range fs {
+		key := o.mapKey(f.Key)
+		if o.flatten && flattenContainers[f.Key] {
+			for _, ff := range flattenContainer(key, f.Value, o.flattenDepth) {
+				k := ff.Key
+				for n := 2; used[k]; n++ {
+					k = ff.Key + "_" + strconv.Itoa(n)
+				}
+				used[k] = true
+				out = append(out, field{k, ff.Value})
+			}
+			continue
+		}
+		out = append(out, field{key, f.Value})
+	}
+
+	if err := checkDuplicateKeys(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// mapKey 按keyMap重命名后的字段名
+func (o encodeOptions) mapKey(key string) string {
+	if to, ok := o.keyMap[key]; ok {
+		return to
+	}
+	return key
+}
+
+// encode 按指定编码方式编码日志内容
+// 编码失败时将ctx、extra等中无法编码的值替换为占位符并记录到_encode_errors后重新编码
+func encode(s Standard, entry *logrus.Entry, data logData, o encodeOptions) ([]byte, error) {
+	output, err := encodeData(s, entry, data, o)
+	if err != nil && replaceUnencodable(data) {
+		return encodeData(s, entry, data, o)
+	}
+
+	return output, err
+}
+
+func encodeData(s Standard, entry *logrus.Entry, data logData, o encodeOptions) ([]byte, error) {
+	switch enc := o.encoding; enc {
+	case "", EncodingJSON:
+		if o.direct() && len(*encodeErrors(data)) == 0 {
+			output, err := o.json().Marshal(data)
+			if err != nil {
+				return nil, errors.Wrapf(err, "json encode %s log", s)
+			}
+			return append(output, '\n'), nil
+		}
+
+		fs, err := o.fields(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "json encode %s log", s)
+		}
+		output, err := encodeJSONFields(o.json(), fs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "json encode %s log", s)
+		}
+		return output, nil
+	case EncodingLogfmt:
+		fs, err := o.fields(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "logfmt encode %s log", s)
+		}
+		output, err := encodeLogfmt(fs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "logfmt encode %s log", s)
+		}
+		return output, nil
+	case EncodingECS:
+		output, err := encodeJSONFields(o.json(), ecsFields(entry, data, o.ecsNamespace))
+		if err != nil {
+			return nil, errors.Wrapf(err, "ecs encode %s log", s)
+		}
+		return output, nil
+	case EncodingCloudLogging:
+		fs, err := o.fields(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cloud logging encode %s log", s)
+		}
+		output, err := encodeJSONFields(o.json(), cloudLoggingFields(entry, data, fs, o.cloudLoggingProject))
+		if err != nil {
+			return nil, errors.Wrapf(err, "cloud logging encode %s log", s)
+		}
+		return output, nil
+	case EncodingOTLP:
+		output, err := encodeJSONFields(o.json(), otlpRequest(entry, data))
+		if err != nil {
+			return nil, errors.Wrapf(err, "otlp encode %s log", s)
+		}
+		return output, nil
+	}
+
+	return nil, errors.Wrapf(ErrEncodingNotFound, "log encoding %q", o.encoding)
+}
+
+// encodeJSONFields 按字段顺序编码为一行json，值为[]field时编码为嵌套对象
+func encodeJSONFields(api jsoniter.API, fs []field) ([]byte, error) {
+	stream := api.BorrowStream(nil)
+	defer api.ReturnStream(stream)
+
+	writeJSONFields(stream, fs)
+	if stream.Error != nil {
+		return nil, stream.Error
+	}
+	stream.WriteRaw("\n")
+
+	return append([]byte(nil), stream.Buffer()...), nil
+}
+
+func writeJSONFields(stream *jsoniter.Stream, fs []field) {
+	stream.WriteObjectStart()
+	for i, f := range fs {
+		if i > 0 {
+			stream.WriteMore()
+		}
+		stream.WriteObjectField(f.Key)
+		writeJSONValue(stream, f.Value)
+	}
+	stream.WriteObjectEnd()
+}
+
+// writeJSONValue []field编码为对象，[]interface{}逐个元素编码，其他类型交给jsoniter
+func writeJSONValue(stream *jsoniter.Stream, v interface{}) {
+	switch v := v.(type) {
+	case []field:
+		writeJSONFields(stream, v)
+	case []interface{}:
+		stream.WriteArrayStart()
+		for i, e := range v {
+			if i > 0 {
+				stream.WriteMore()
+			}
+			writeJSONValue(stream, e)
+		}
+		stream.WriteArrayEnd()
+	default:
+		stream.WriteVal(v)
+	}
+}
+
+var (
+	// plainJSON 用于将任意值转换为基础类型，数字保持原样
+	plainJSON = jsoniter.Config{UseNumber: true}.Froze()
+	// sortedJSON 与jsoniter.ConfigDefault一致，但递归地按key排序map
+	sortedJSON = jsoniter.Config{EscapeHTML: true, SortMapKeys: true}.Froze()
+)
+
+// toPlain 按json规则将任意值(结构体、自定义map与slice等)转换为
+// map[string]interface{}、[]interface{}、string、bool、jsoniter.Number或nil
+func toPlain(v interface{}) (interface{}, error) {
+	b, err := plainJSON.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var plain interface{}
+	if err := plainJSON.Unmarshal(b, &plain); err != nil {
+		return nil, err
+	}
+
+	return plain, nil
+}
+
+func (d *APPLogsV1Data) release() {
+	appLogsV1Pool.Put(d)
+}
+
+// fields 按json输出顺序返回字段，忽略omitempty的空值字段
+func (d *APPLogsV1Data) fields() []field {
+	fs := make([]field, 0, 9)
+	fs = append(fs, field{"schema", d.Schema})
+	if d.Service != "" {
+		fs = append(fs, field{"service", d.Service})
+	}
+	if d.Environment != "" {
+		fs = append(fs, field{"env", d.Environment})
+	}
+	if d.Metadata != nil {
+		fs = append(fs, field{"meta", d.Metadata})
+	}
+	fs = append(fs,
+		field{"channel", d.Channel},
+		field{"level", d.Level},
+	)
+	fs = timeFields(fs, d.Time, d.epoch)
+	fs = append(fs, field{"msg", d.Message})
+	if len(d.Context) > 0 {
+		fs = append(fs, field{"ctx", d.Context})
+	}
+
+	fs = encodeErrorFields(fs, d.encodeErrors)
+
+	return truncationFields(fs, d.truncations, d.collapsed)
+}
+
+func (d *HTTPRequestV1Data) release() {
+	httpRequestV1Pool.Put(d)
+}
+
+// fields 按json输出顺序返回字段，忽略omitempty的空值字段
+func (d *HTTPRequestV1Data) fields() []field {
+	fs := make([]field, 0, 14)
+	fs = append(fs, field{"schema", d.Schema})
+	if d.Service != "" {
+		fs = append(fs, field{"service", d.Service})
+	}
+	if d.Environment != "" {
+		fs = append(fs, field{"env", d.Environment})
+	}
+	if d.Metadata != nil {
+		fs = append(fs, field{"meta", d.Metadata})
+	}
+	fs = append(fs,
+		field{"level", d.Level},
+	)
+	fs = timeFields(fs, d.Time, d.epoch)
+	fs = append(fs,
+		field{"ip", d.IP},
+		field{"method", d.Method},
+		field{"path", d.Path},
+	)
+	if d.User != "" {
+		fs = append(fs, field{"user", d.User})
+	}
+	if len(d.Headers) > 0 {
+		fs = append(fs, field{"headers", d.Headers})
+	}
+	if len(d.Get) > 0 {
+		fs = append(fs, field{"get", d.Get})
+	}
+	if len(d.Post) > 0 {
+		fs = append(fs, field{"post", d.Post})
+	}
+	if len(d.Extra) > 0 {
+		fs = append(fs, field{"extra", d.Extra})
+	}
+
+	fs = encodeErrorFields(fs, d.encodeErrors)
+
+	return truncationFields(fs, d.truncations, d.collapsed)
+}
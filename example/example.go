@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"time"
 
 	"github.com/cowsvagina/go-logger"
 	"github.com/pkg/errors"
@@ -19,21 +20,22 @@ func main() {
 }
 
 func appLogsV1Example() {
-	// 创建app.logs.v1规范的日志对象
-	al, err := logger.NewLogger(logger.APPLogsV1)
+	// 创建app.logs.v1规范的日志对象，al 是标准的logrus.Logger
+	al, err := logger.NewLogger(
+		logger.APPLogsV1,
+		logger.WithService("example"),
+		logger.WithEnvironment("dev"),
+		// 附加主机名、进程号、版本号及Kubernetes信息
+		logger.WithMetadata(logger.NewMetadata("v0.0.1")),
+		// 修改输出日期格式，默认time.RFC3339
+		logger.WithTimeLayout(time.RFC3339Nano),
+		logger.WithLevel(logrus.DebugLevel),
+		logger.WithOutput(os.Stdout),
+	)
 	if err != nil {
 		panic(err)
 	}
 
-	// al 是标准的logrus.Logger
-	al.SetLevel(logrus.DebugLevel)
-	al.SetOutput(os.Stdout)
-
-	// 修改输出日期格式，默认time.RFC3339
-	// if f, ok := al.Formatter.(*logger.APPLogsV1Formatter); ok {
-	// 	f.TimeLayout = time.RFC3339Nano
-	// }
-
 	// OUTPUT: {"schema":"app.logs.v1","t":"2019-08-12T10:13:48.837899+08:00","l":"debug","c":"TEST","m":"test app.logs.v1 log","ctx":{"foo":"bar","error":"wow","stackTrace":["main.main /home/hsldymq/Development/Go/src/github.com/cowsvagina/go-logger/example/example.go:37","runtime.main /usr/local/opt/go/libexec/src/runtime/proc.go:200","runtime.goexit /usr/local/opt/go/libexec/src/runtime/asm_amd64.s:1337"]}}
 	al.WithFields(logrus.Fields{
 		"channel": "TEST",
@@ -45,16 +47,16 @@ func appLogsV1Example() {
 
 func httpRequestV1Example() {
 	// 创建 http.request.v1规范的日志对象
-	hl, err := logger.NewLogger(logger.HTTPRequestV1)
+	hl, err := logger.NewLogger(
+		logger.HTTPRequestV1,
+		logger.WithService("example"),
+		logger.WithEnvironment("dev"),
+		logger.WithOutput(os.Stdout),
+	)
 	if err != nil {
 		panic(err)
 	}
 
-	// 修改输出日期格式，默认time.RFC3339
-	// if f, ok := hl.Formatter.(*logger.HTTPRequestV1Formatter); ok {
-	// 	f.TimeLayout = time.RFC3339Nano
-	// }
-
 	headers := http.Header{}
 	headers.Set("x-test", "1")
 
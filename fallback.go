@@ -0,0 +1,285 @@
+package logger
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// EncodeErrorsKey 记录无法编码的值的字段名
+	EncodeErrorsKey = "_encode_errors"
+	// encodeErrorCyclic 循环引用的值无法编码的原因
+	encodeErrorCyclic = "cyclic structure"
+)
+
+// encodeError 无法编码的值的记录
+type encodeError struct {
+	Path   string
+	Type   string
+	Reason string
+}
+
+// encodeErrorFields 存在无法编码的值时在字段末尾追加_encode_errors
+func encodeErrorFields(fs []field, es []encodeError) []field {
+	if len(es) == 0 {
+		return fs
+	}
+
+	sort.Slice(es, func(i, j int) bool {
+		return es[i].Path < es[j].Path
+	})
+	list := make([]interface{}, len(es))
+	for i, e := range es {
+		list[i] = []field{{"key", e.Path}, {"type", e.Type}, {"error", e.Reason}}
+	}
+
+	return append(fs, field{EncodeErrorsKey, list})
+}
+
+// encodePlaceholder 替换无法编码的值的占位符，如"!unencodable(chan int: chan int is unsupported type)"
+func encodePlaceholder(typ, reason string) string {
+	return "!unencodable(" + typ + ": " + reason + ")"
+}
+
+// encodeErrors 日志内容中无法编码的值的记录
+func encodeErrors(data logData) *[]encodeError {
+	switch d := data.(type) {
+	case *APPLogsV1Data:
+		return &d.encodeErrors
+	case *HTTPRequestV1Data:
+		return &d.encodeErrors
+	}
+
+	return nil
+}
+
+// replaceCyclic 将容器中存在循环引用的值替换为占位符，避免编码时无限递归
+// m为提取日志内容时新建的map，可直接修改
+func replaceCyclic(path string, m map[string]interface{}, es *[]encodeError) {
+	for k, v := range m {
+		if isCyclic(v) {
+			t := reflect.TypeOf(v).String()
+			*es = append(*es, encodeError{path + "." + k, t, encodeErrorCyclic})
+			m[k] = encodePlaceholder(t, encodeErrorCyclic)
+		}
+	}
+}
+
+// isCyclic 值中是否存在循环引用，只检查json编码时会访问的导出字段
+func isCyclic(v interface{}) bool {
+	var stack [8]uintptr
+	return cyclic(v, stack[:0])
+}
+
+// cyclic visiting为当前路径上的指针、map与slice，常见的map与slice不经过反射
+func cyclic(v interface{}, visiting []uintptr) bool {
+	switch x := v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, []string, error:
+		return false
+	case logrus.Fields:
+		return cyclicMap(x, visiting)
+	case map[string]interface{}:
+		return cyclicMap(x, visiting)
+	case []interface{}:
+		if len(x) == 0 {
+			return false
+		}
+		p := reflect.ValueOf(x).Pointer()
+		if visited(visiting, p) {
+			return true
+		}
+		visiting = append(visiting, p)
+		for _, e := range x {
+			if cyclic(e, visiting) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return cyclicValue(reflect.ValueOf(v), visiting)
+}
+
+func cyclicMap(m map[string]interface{}, visiting []uintptr) bool {
+	if len(m) == 0 {
+		return false
+	}
+	p := reflect.ValueOf(m).Pointer()
+	if visited(visiting, p) {
+		return true
+	}
+	visiting = append(visiting, p)
+	for _, e := range m {
+		if cyclic(e, visiting) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func visited(visiting []uintptr, p uintptr) bool {
+	for _, v := range visiting {
+		if v == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+func cyclicValue(v reflect.Value, visiting []uintptr) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() || !mayContainRefs(v.Type().Elem()) {
+			return false
+		}
+		p := v.Pointer()
+		if visited(visiting, p) {
+			return true
+		}
+		visiting = append(visiting, p)
+
+		switch v.Kind() {
+		case reflect.Ptr:
+			return cyclicValue(v.Elem(), visiting)
+		case reflect.Map:
+			for it := v.MapRange(); it.Next(); {
+				if cyclicValue(it.Value(), visiting) {
+					return true
+				}
+			}
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				if cyclicValue(v.Index(i), visiting) {
+					return true
+				}
+			}
+		}
+	case reflect.Array:
+		if !mayContainRefs(v.Type().Elem()) {
+			return false
+		}
+		for i := 0; i < v.Len(); i++ {
+			if cyclicValue(v.Index(i), visiting) {
+				return true
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if f := t.Field(i); f.PkgPath == "" || f.Anonymous {
+				if cyclicValue(v.Field(i), visiting) {
+					return true
+				}
+			}
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return false
+		}
+		if !v.CanInterface() {
+			return cyclicValue(v.Elem(), visiting)
+		}
+		return cyclic(v.Elem().Interface(), visiting)
+	}
+
+	return false
+}
+
+// mayContainRefs 该类型的值是否可能包含指针、map、slice或interface
+func mayContainRefs(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Array, reflect.Struct, reflect.Interface:
+		return true
+	}
+
+	return false
+}
+
+// replaceUnencodable 将ctx、extra、get、post中无法编码的值替换为占位符，返回是否有值被替换
+func replaceUnencodable(data logData) bool {
+	es := encodeErrors(data)
+	if es == nil {
+		return false
+	}
+
+	n := len(*es)
+	switch d := data.(type) {
+	case *APPLogsV1Data:
+		replaceUnencodableMap("ctx", d.Context, es)
+	case *HTTPRequestV1Data:
+		replaceUnencodableMap("get", d.Get, es)
+		replaceUnencodableMap("post", d.Post, es)
+		replaceUnencodableMap("extra", d.Extra, es)
+	}
+
+	return len(*es) > n
+}
+
+// replaceUnencodableMap 直接修改m，m为提取日志内容时新建的map
+func replaceUnencodableMap(path string, m map[string]interface{}, es *[]encodeError) {
+	for k, v := range m {
+		m[k] = replaceUnencodableValue(path+"."+k, v, es)
+	}
+}
+
+// replaceUnencodableValue 返回替换后的值，嵌套的map与slice只替换其中无法编码的元素，不修改原有的值
+func replaceUnencodableValue(path string, v interface{}, es *[]encodeError) interface{} {
+	switch x := v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v
+	case float32:
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return unencodable(path, v, fmt.Sprintf("unsupported value: %v", x), es)
+		}
+		return v
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return unencodable(path, v, fmt.Sprintf("unsupported value: %v", x), es)
+		}
+		return v
+	}
+
+	_, err := jsoniter.ConfigDefault.Marshal(v)
+	if err == nil {
+		return v
+	}
+
+	switch x := v.(type) {
+	case logrus.Fields:
+		return replaceUnencodableCopy(path, x, es)
+	case map[string]interface{}:
+		return replaceUnencodableCopy(path, x, es)
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, e := range x {
+			out[i] = replaceUnencodableValue(path+"."+strconv.Itoa(i), e, es)
+		}
+		return out
+	}
+
+	return unencodable(path, v, err.Error(), es)
+}
+
+func replaceUnencodableCopy(path string, m map[string]interface{}, es *[]encodeError) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = replaceUnencodableValue(path+"."+k, v, es)
+	}
+
+	return out
+}
+
+func unencodable(path string, v interface{}, reason string, es *[]encodeError) string {
+	t, reason := describeUnencodable(v, reason)
+	*es = append(*es, encodeError{path, t, reason})
+
+	return encodePlaceholder(t, reason)
+}
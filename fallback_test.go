@@ -0,0 +1,148 @@
+package logger
+
+import (
+	"math"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+type cyclicNode struct {
+	Name string
+	Next *cyclicNode
+}
+
+func TestFormatterUnencodable(t *testing.T) {
+	now := time.Date(2019, 8, 12, 10, 13, 48, 0, time.UTC)
+
+	t.Run("APPLogsV1", func(t *testing.T) {
+		node := &cyclicNode{Name: "a"}
+		node.Next = node
+		nested := map[string]interface{}{"ok": 1, "nan": math.NaN()}
+
+		f, _ := NewFormatter(APPLogsV1, WithSortedKeys())
+		data, err := f.Format(&logrus.Entry{
+			Level:   logrus.InfoLevel,
+			Time:    now,
+			Message: "hello",
+			Data: logrus.Fields{
+				"ch":     make(chan int),
+				"fn":     func() {},
+				"node":   node,
+				"nested": nested,
+				"id":     1,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		expected := `{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","msg":"hello",` +
+			`"ctx":{"ch":"!unencodable(chan int: chan int is unsupported type)",` +
+			`"fn":"!unencodable(func(): func() is unsupported type)","id":1,` +
+			`"nested":{"nan":"!unencodable(float64: unsupported value: NaN)","ok":1},` +
+			`"node":"!unencodable(*logger.cyclicNode: cyclic structure)"},` +
+			`"_encode_errors":[` +
+			`{"key":"ctx.ch","type":"chan int","error":"chan int is unsupported type"},` +
+			`{"key":"ctx.fn","type":"func()","error":"func() is unsupported type"},` +
+			`{"key":"ctx.nested.nan","type":"float64","error":"unsupported value: NaN"},` +
+			`{"key":"ctx.node","type":"*logger.cyclicNode","error":"cyclic structure"}]}` + "\n"
+		if string(data) != expected {
+			t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, data)
+		}
+
+		// 原有的值不应被修改
+		if v, ok := nested["nan"].(float64); !ok || !math.IsNaN(v) {
+			t.Fatal("Format() modified original ctx value")
+		}
+	})
+
+	t.Run("ECS", func(t *testing.T) {
+		f, _ := NewFormatter(APPLogsV1, WithEncoding(EncodingECS))
+		data, err := f.Format(&logrus.Entry{
+			Level:   logrus.InfoLevel,
+			Time:    now,
+			Message: "hello",
+			Data:    logrus.Fields{"ch": make(chan int), "id": 1},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		cases := []struct {
+			path     []interface{}
+			expected string
+		}{
+			{path: []interface{}{DefaultECSNamespace, "ctx", "ch"}, expected: "!unencodable(chan int: chan int is unsupported type)"},
+			{path: []interface{}{DefaultECSNamespace, "ctx", "id"}, expected: "1"},
+			{path: []interface{}{EncodeErrorsKey, 0, "key"}, expected: "ctx.ch"},
+			{path: []interface{}{EncodeErrorsKey, 0, "type"}, expected: "chan int"},
+		}
+		for _, c := range cases {
+			if v := jsoniter.Get(data, c.path...).ToString(); v != c.expected {
+				t.Fatalf(`Format() output %q, Expected=%q, Actual=%q`, c.path, c.expected, v)
+			}
+		}
+	})
+
+	t.Run("OTLP", func(t *testing.T) {
+		node := &cyclicNode{Name: "a"}
+		node.Next = node
+
+		f, _ := NewFormatter(APPLogsV1, WithEncoding(EncodingOTLP))
+		data, err := f.Format(&logrus.Entry{
+			Level:   logrus.InfoLevel,
+			Time:    now,
+			Message: "hello",
+			Data:    logrus.Fields{"node": node},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		attrs := jsoniter.Get(data, "resourceLogs", 0, "scopeLogs", 0, "logRecords", 0, "attributes")
+		if v := otlpAttribute(attrs, "node").Get("stringValue").ToString(); v != "!unencodable(*logger.cyclicNode: cyclic structure)" {
+			t.Fatalf(`Format() output "node", Expected=%q, Actual=%q`, "!unencodable(*logger.cyclicNode: cyclic structure)", v)
+		}
+		item := otlpAttribute(attrs, EncodeErrorsKey).Get("arrayValue", "values", 0, "kvlistValue", "values")
+		if v := otlpAttribute(item, "key").Get("stringValue").ToString(); v != "ctx.node" {
+			t.Fatalf(`Format() output "_encode_errors.0.key", Expected=%q, Actual=%q`, "ctx.node", v)
+		}
+	})
+
+	t.Run("HTTPRequestV1", func(t *testing.T) {
+		m := map[string]interface{}{}
+		m["self"] = m
+
+		f, _ := NewFormatter(HTTPRequestV1, WithEncoding(EncodingLogfmt))
+		data, err := f.Format(&logrus.Entry{
+			Level: logrus.InfoLevel,
+			Time:  now,
+			Data: logrus.Fields{
+				HTTPRequestReqKey: &http.Request{
+					Method:     http.MethodGet,
+					URL:        &url.URL{Path: "/"},
+					RemoteAddr: "127.0.0.1:80",
+				},
+				"ch":  make(chan int),
+				"map": m,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		expected := `schema=http.request.v1 level=info time=2019-08-12T10:13:48Z ip=127.0.0.1 method=GET path=/ ` +
+			`extra.ch="!unencodable(chan int: chan int is unsupported type)" ` +
+			`extra.map="!unencodable(map[string]interface {}: cyclic structure)" ` +
+			`_encode_errors.0.key=extra.ch _encode_errors.0.type="chan int" _encode_errors.0.error="chan int is unsupported type" ` +
+			`_encode_errors.1.key=extra.map _encode_errors.1.type="map[string]interface {}" _encode_errors.1.error="cyclic structure"` + "\n"
+		if string(data) != expected {
+			t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, data)
+		}
+	})
+}
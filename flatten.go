@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// flattenContainers 开启展开时，以"."连接的key展开到顶层的容器字段
+	flattenContainers = map[string]bool{
+		"ctx":   true,
+		"extra": true,
+		"get":   true,
+		"post":  true,
+	}
+)
+
+// flattenContainer 将容器字段展开为以prefix开头、"."连接的顶层字段，按key排序返回
+//
+// depth为容器之下最多展开的层数，小于等于0时不限制，超过层数的值保持为嵌套对象；
+// 数组不展开，保持原样作为值输出；
+// 展开得到的key与容器中原本就包含"."的key(如ctx中的"user.id"与"user":{"id":1})冲突时，
+// 保留原有key的值，展开得到的值依次使用"_2"、"_3"...后缀
+func flattenContainer(prefix string, value interface{}, depth int) []field {
+	m, ok := stringMap(value)
+	if !ok {
+		return []field{{prefix, value}}
+	}
+
+	out := make(map[string]interface{}, len(m))
+	var derived []field
+	for k, v := range m {
+		key := prefix + "." + k
+		if sub, ok := stringMap(v); ok && depth != 1 && len(sub) > 0 {
+			flattenInto(&derived, key, sub, depth-1)
+			continue
+		}
+		out[key] = v
+	}
+
+	sort.Slice(derived, func(i, j int) bool {
+		return derived[i].Key < derived[j].Key
+	})
+	for _, f := range derived {
+		key := f.Key
+		for n := 2; ; n++ {
+			if _, ok := out[key]; !ok {
+				break
+			}
+			key = f.Key + "_" + strconv.Itoa(n)
+		}
+		out[key] = f.Value
+	}
+
+	keys := make([]string, 0, len(out))
+	for k := range out {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fs := make([]field, len(keys))
+	for i, k := range keys {
+		fs[i] = field{k, out[k]}
+	}
+
+	return fs
+}
+
+func flattenInto(out *[]field, prefix string, m map[string]interface{}, depth int) {
+	for k, v := range m {
+		key := prefix + "." + k
+		if sub, ok := stringMap(v); ok && depth != 1 && len(sub) > 0 {
+			flattenInto(out, key, sub, depth-1)
+			continue
+		}
+		*out = append(*out, field{key, v})
+	}
+}
+
+// stringMap 将key为字符串的map转换为map[string]interface{}
+func stringMap(v interface{}) (map[string]interface{}, bool) {
+	switch v := v.(type) {
+	case logrus.Fields:
+		return v, true
+	case map[string]interface{}:
+		return v, true
+	case map[string]string:
+		m := make(map[string]interface{}, len(v))
+		for k, s := range v {
+			m[k] = s
+		}
+		return m, true
+	case nil:
+		return nil, false
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+
+	m := make(map[string]interface{}, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		m[iter.Key().String()] = iter.Value().Interface()
+	}
+	return m, true
+}
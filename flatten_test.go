@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestFlatten(t *testing.T) {
+	entry := &logrus.Entry{
+		Level:   logrus.InfoLevel,
+		Time:    time.Date(2019, 8, 12, 10, 13, 48, 0, time.UTC),
+		Message: "hello",
+		Data: logrus.Fields{
+			"user":    map[string]interface{}{"id": 1, "profile": map[string]string{"name": "a"}},
+			"user.id": 2,
+			"tags":    []string{"x", "y"},
+			"empty":   map[string]int{},
+		},
+	}
+
+	cases := []struct {
+		opts     []Option
+		expected string
+	}{
+		{
+			opts: []Option{WithFlatten(0)},
+			expected: `{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","msg":"hello",` +
+				`"ctx.empty":{},"ctx.tags":["x","y"],"ctx.user.id":2,"ctx.user.id_2":1,"ctx.user.profile.name":"a"}`,
+		},
+		{
+			opts: []Option{WithFlatten(2)},
+			expected: `{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","msg":"hello",` +
+				`"ctx.empty":{},"ctx.tags":["x","y"],"ctx.user.id":2,"ctx.user.id_2":1,"ctx.user.profile":{"name":"a"}}`,
+		},
+		{
+			opts: []Option{WithFlatten(1), WithKeyMap(map[string]string{"ctx": "c"})},
+			expected: `{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","msg":"hello",` +
+				`"c.empty":{},"c.tags":["x","y"],"c.user":{"id":1,"profile":{"name":"a"}},"c.user.id":2}`,
+		},
+		{
+			// 展开得到的key与顶层字段冲突
+			opts: []Option{WithFlatten(1), WithKeyMap(map[string]string{"msg": "ctx.empty"})},
+			expected: `{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","ctx.empty":"hello",` +
+				`"ctx.empty_2":{},"ctx.tags":["x","y"],"ctx.user":{"id":1,"profile":{"name":"a"}},"ctx.user.id":2}`,
+		},
+		{
+			opts: []Option{WithFlatten(1), WithEpochField("ctx.tags", time.Second)},
+			expected: `{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","ctx.tags":1565604828,"msg":"hello",` +
+				`"ctx.empty":{},"ctx.tags_2":["x","y"],"ctx.user":{"id":1,"profile":{"name":"a"}},"ctx.user.id":2}`,
+		},
+	}
+
+	for i, c := range cases {
+		f, err := NewFormatter(APPLogsV1, append(c.opts, WithSortedKeys())...)
+		if err != nil {
+			t.Fatalf("Test NewFormatter() case %d, Expected=nil, Actual=%q", i, err.Error())
+		}
+		data, err := f.Format(entry)
+		if err != nil {
+			t.Fatalf("Format() case %d error, Expected=nil, Actual=%q", i, err.Error())
+		}
+		if string(data) != c.expected+"\n" {
+			t.Fatalf("Format() case %d output, Expected=%q, Actual=%q", i, c.expected, data)
+		}
+	}
+
+	f, _ := NewFormatter(HTTPRequestV1, WithFlatten(0))
+	data, err := f.Format(&logrus.Entry{
+		Level: logrus.InfoLevel,
+		Time:  time.Date(2019, 8, 12, 10, 13, 48, 0, time.UTC),
+		Data: logrus.Fields{
+			HTTPRequestReqKey: &http.Request{
+				RemoteAddr: "1.2.3.4:1234",
+				Method:     http.MethodPost,
+				URL:        &url.URL{Path: "/api", RawQuery: "q=1"},
+				PostForm:   url.Values{"p": []string{"2"}},
+			},
+			"runtime": logrus.Fields{"ms": 3},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+	}
+	expected := `{"schema":"http.request.v1","level":"info","time":"2019-08-12T10:13:48Z","ip":"1.2.3.4","method":"POST","path":"/api",` +
+		`"get.q":"1","post.p":"2","extra.runtime.ms":3}` + "\n"
+	if string(data) != expected {
+		t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, data)
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"sync"
 	"time"
 
-	jsoniter "github.com/json-iterator/go"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
@@ -16,8 +15,15 @@ import (
 const (
 	HTTPRequestReqKey  = "request"
 	HTTPRequestUserKey = "user"
+	// 以下字段仍输出在extra中，部分编码方式会额外将其映射到对应字段
+	HTTPRequestStatusKey  = "status"
+	HTTPRequestLatencyKey = "latency"
 
 	ChannelKey = "channel"
+
+	// 链路追踪ID，仍输出在ctx或extra中，部分编码方式会额外将其映射到对应字段
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
 )
 
 var (
@@ -26,6 +32,8 @@ var (
 
 	_ logrus.Formatter = (*APPLogsV1Formatter)(nil)
 	_ logrus.Formatter = (*HTTPRequestV1Formatter)(nil)
+	_ extractor        = (*APPLogsV1Formatter)(nil)
+	_ extractor        = (*HTTPRequestV1Formatter)(nil)
 
 	emptyStack = make([]string, 0)
 
@@ -44,22 +52,84 @@ var (
 			}
 		},
 	}
+
+	// formatterFactories 已注册的日志规范及其格式化对象的创建方法
+	formatterFactories = map[Standard]func(o *options) logrus.Formatter{
+		APPLogsV1: func(o *options) logrus.Formatter {
+			return &APPLogsV1Formatter{
+				TimeLayout:          o.timeLayout,
+				TimeLocation:        o.timeLocation,
+				EpochUnit:           o.epochUnit,
+				EpochKey:            o.epochKey,
+				Service:             o.service,
+				Environment:         o.environment,
+				Metadata:            o.metadata,
+				Encoding:            o.encoding,
+				ECSNamespace:        o.ecsNamespace,
+				CloudLoggingProject: o.cloudLoggingProject,
+				KeyMap:              o.keyMap,
+				SortKeys:            o.sortKeys,
+				Flatten:             o.flatten,
+				FlattenDepth:        o.flattenDepth,
+				Limits:              o.limits,
+				TypeStable:          o.typeStable,
+				ValueEncoders:       o.valueEncoders,
+				DefaultChannel:      o.defaultChannel,
+				RequireChannel:      o.requireChannel,
+			}
+		},
+		HTTPRequestV1: func(o *options) logrus.Formatter {
+			return &HTTPRequestV1Formatter{
+				TimeLayout:          o.timeLayout,
+				TimeLocation:        o.timeLocation,
+				EpochUnit:           o.epochUnit,
+				EpochKey:            o.epochKey,
+				Service:             o.service,
+				Environment:         o.environment,
+				Metadata:            o.metadata,
+				Encoding:            o.encoding,
+				ECSNamespace:        o.ecsNamespace,
+				CloudLoggingProject: o.cloudLoggingProject,
+				KeyMap:              o.keyMap,
+				SortKeys:            o.sortKeys,
+				Flatten:             o.flatten,
+				FlattenDepth:        o.flattenDepth,
+				Limits:              o.limits,
+				TypeStable:          o.typeStable,
+				ValueEncoders:       o.valueEncoders,
+			}
+		},
+	}
 )
 
 // NewFormatter 获得日志规范对应的格式化对象
-func NewFormatter(s Standard) (logrus.Formatter, error) {
-	switch s {
-	case APPLogsV1:
-		return &APPLogsV1Formatter{
-			TimeLayout: time.RFC3339,
-		}, nil
-	case HTTPRequestV1:
-		return &HTTPRequestV1Formatter{
-			TimeLayout: time.RFC3339,
-		}, nil
+func NewFormatter(s Standard, opts ...Option) (logrus.Formatter, error) {
+	return newFormatter(s, newOptions(opts))
+}
+
+func newFormatter(s Standard, o *options) (logrus.Formatter, error) {
+	factory, ok := formatterFactories[s]
+	if !ok {
+		return nil, errors.Wrapf(ErrFormatterNotFound, "log standard %q", s)
+	}
+	if !validEncoding(o.encoding) {
+		return nil, errors.Wrapf(ErrEncodingNotFound, "log encoding %q", o.encoding)
+	}
+
+	if o.epoch && !validEpochUnit(o.epochUnit) {
+		return nil, errors.Wrapf(ErrInvalidEpochUnit, "log epoch unit %s", o.epochUnit)
+	}
+	// ECS与OTLP编码使用固定的字段名，不能重命名字段
+	if len(o.keyMap) > 0 && (o.encoding == EncodingECS || o.encoding == EncodingOTLP) {
+		return nil, errors.Wrapf(ErrOptionNotSupported, "key map for log encoding %q", o.encoding)
+	}
+	if len(o.keyMap) > 0 || o.epochKey != "" {
+		if err := validateKeys(s, o.keyMap, o.epochKey, o.encoding); err != nil {
+			return nil, err
+		}
 	}
 
-	return nil, errors.Wrapf(ErrFormatterNotFound, "log standard %q", s)
+	return factory(o), nil
 }
 
 // APPLogsV1Data app.logs.v1日志输出内容
@@ -67,55 +137,129 @@ type APPLogsV1Data struct {
 	Schema      string                 `json:"schema"`
 	Service     string                 `json:"service,omitempty"`
 	Environment string                 `json:"env,omitempty"`
+	Metadata    *Metadata              `json:"meta,omitempty"`
 	Channel     string                 `json:"channel"`
 	Level       string                 `json:"level"`
 	Time        string                 `json:"time"`
 	Message     string                 `json:"msg"`
 	Context     map[string]interface{} `json:"ctx,omitempty"`
+
+	epoch        timeEpoch
+	truncations  []truncation
+	encodeErrors []encodeError
+	// 截断记录超出单行限制时只输出截断的次数
+	collapsed bool
 }
 
 // APPLogsV1Formatter app.logs.v1日志格式化
 type APPLogsV1Formatter struct {
 	// 时间格式，默认ISO8601，精确到秒
-	TimeLayout  string
+	TimeLayout string
+	// 输出时间使用的时区，为nil时使用日志时间自身的时区
+	TimeLocation *time.Location
+	// 以json数字输出的Unix时间戳精度，只能为time.Second、time.Millisecond、time.Microsecond或time.Nanosecond，其他值时不输出
+	EpochUnit time.Duration
+	// Unix时间戳的字段名，为空时时间戳替换time字段输出，否则与time字段同时输出
+	EpochKey    string
 	Service     string
 	Environment string
+	// 服务实例元信息，为nil时不输出
+	Metadata *Metadata
+	// 编码方式，默认json
+	Encoding Encoding
+	// EncodingECS编码时自定义字段所在的命名空间，默认"custom"
+	ECSNamespace string
+	// EncodingCloudLogging编码时trace所属的GCP项目ID
+	CloudLoggingProject string
+	// 顶层字段名映射，如{"msg": "message"}，对json、logfmt与cloud-logging编码生效
+	KeyMap map[string]string
+	// 是否递归地按key排序输出ctx、extra、headers等嵌套的map，logfmt编码始终排序
+	SortKeys bool
+	// 是否将ctx(app.logs.v1)或extra、get、post(http.request.v1)展开为"."连接的顶层字段，
+	// 如"ctx.user.id"，对json、logfmt与cloud-logging编码生效
+	Flatten bool
+	// 容器之下最多展开的层数，小于等于0时不限制
+	FlattenDepth int
+	// 日志内容的大小限制，超出时截断
+	Limits Limits
+	// 保持ctx等容器中同名字段类型稳定的方式
+	TypeStable TypeStable
+	// 按类型注册的值转换方式，优先于内置的转换方式
+	ValueEncoders map[reflect.Type]ValueEncoder
+	// 日志条目缺少channel时使用的channel
+	DefaultChannel string
+	// 是否拒绝缺少channel且未设置DefaultChannel的日志条目，Format返回ErrChannelRequired
+	RequireChannel bool
 }
 
 // Format implements logrus.Formatter interface
 func (af *APPLogsV1Formatter) Format(entry *logrus.Entry) ([]byte, error) {
-	channel := ""
+	if _, err := af.channel(entry); err != nil {
+		return nil, err
+	}
+
+	if o := af.encodeOptions(); o.streaming() && !af.TypeStable.enabled() {
+		return af.formatJSON(entry, o)
+	}
+
+	data := af.data(entry)
+	af.TypeStable.apply(data)
+	output, err := af.Limits.encode(data, func() ([]byte, error) {
+		return encode(APPLogsV1, entry, data, af.encodeOptions())
+	})
+	appLogsV1Pool.Put(data)
+
+	return output, err
+}
+
+func (af *APPLogsV1Formatter) encodeOptions() encodeOptions {
+	return encodeOptions{
+		encoding:            af.Encoding,
+		ecsNamespace:        af.ECSNamespace,
+		cloudLoggingProject: af.CloudLoggingProject,
+		keyMap:              af.KeyMap,
+		epoch:               validEpochUnit(af.EpochUnit),
+		sortKeys:            af.SortKeys,
+		flatten:             af.Flatten,
+		flattenDepth:        af.FlattenDepth,
+		limits:              af.Limits.enabled(),
+	}
+}
+
+func (af *APPLogsV1Formatter) extract(entry *logrus.Entry) (logData, error) {
+	if _, err := af.channel(entry); err != nil {
+		return nil, err
+	}
+
+	return af.data(entry), nil
+}
+
+// data 从日志条目中提取app.logs.v1日志内容，使用完毕后应放回appLogsV1Pool
+func (af *APPLogsV1Formatter) data(entry *logrus.Entry) *APPLogsV1Data {
+	channel, _ := af.channel(entry)
 	context := logrus.Fields{}
 	for k, v := range entry.Data {
-		switch k {
-		case ChannelKey:
-			channel, _ = v.(string)
-		default:
-			if err, ok := v.(error); ok {
-				context[k] = makeErrInfo(err)
-				continue
-			}
+		if k != ChannelKey {
 			context[k] = v
 		}
 	}
 
 	data := appLogsV1Pool.Get().(*APPLogsV1Data)
-	data.Time = entry.Time.Format(af.TimeLayout)
-	data.Level = entry.Level.String()
+	data.Time, data.epoch = formatTime(entry.Time, af.TimeLocation, af.TimeLayout, af.EpochUnit, af.EpochKey)
+	data.Level = levelName(entry.Level)
 	data.Service = af.Service
 	data.Channel = channel
 	data.Environment = af.Environment
+	data.Metadata = af.Metadata
 	data.Message = entry.Message
 	data.Context = context
+	data.truncations = data.truncations[:0]
+	data.collapsed = false
+	data.encodeErrors = data.encodeErrors[:0]
+	replaceCyclic("ctx", data.Context, &data.encodeErrors)
+	valueEncoding{af.ValueEncoders, af.TimeLocation}.encodeFields(data.Context)
 
-	output, err := jsoniter.Marshal(data)
-	if err != nil {
-		appLogsV1Pool.Put(data)
-		return nil, errors.Wrapf(err, "json encode %s log", APPLogsV1)
-	}
-
-	appLogsV1Pool.Put(data)
-	return append(output, '\n'), nil
+	return data
 }
 
 // HTTPRequestV1Data http.request.v1日志输出内容
@@ -123,6 +267,7 @@ type HTTPRequestV1Data struct {
 	Schema      string            `json:"schema"`
 	Service     string            `json:"service,omitempty"`
 	Environment string            `json:"env,omitempty"`
+	Metadata    *Metadata         `json:"meta,omitempty"`
 	Level       string            `json:"level"`
 	Time        string            `json:"time"`
 	IP          string            `json:"ip"`
@@ -133,26 +278,94 @@ type HTTPRequestV1Data struct {
 	Get         logrus.Fields     `json:"get,omitempty"`
 	Post        logrus.Fields     `json:"post,omitempty"`
 	Extra       logrus.Fields     `json:"extra,omitempty"`
+
+	epoch        timeEpoch
+	truncations  []truncation
+	encodeErrors []encodeError
+	// 截断记录超出单行限制时只输出截断的次数
+	collapsed bool
 }
 
 // HTTPRequestV1Formatter http.request.v1日志格式化
 type HTTPRequestV1Formatter struct {
 	// 时间格式，默认ISO8601，精确到秒
-	TimeLayout  string
+	TimeLayout string
+	// 输出时间使用的时区，为nil时使用日志时间自身的时区
+	TimeLocation *time.Location
+	// 以json数字输出的Unix时间戳精度，只能为time.Second、time.Millisecond、time.Microsecond或time.Nanosecond，其他值时不输出
+	EpochUnit time.Duration
+	// Unix时间戳的字段名，为空时时间戳替换time字段输出，否则与time字段同时输出
+	EpochKey    string
 	Service     string
 	Environment string
+	// 服务实例元信息，为nil时不输出
+	Metadata *Metadata
+	// 编码方式，默认json
+	Encoding Encoding
+	// EncodingECS编码时自定义字段所在的命名空间，默认"custom"
+	ECSNamespace string
+	// EncodingCloudLogging编码时trace所属的GCP项目ID
+	CloudLoggingProject string
+	// 顶层字段名映射，如{"msg": "message"}，对json、logfmt与cloud-logging编码生效
+	KeyMap map[string]string
+	// 是否递归地按key排序输出ctx、extra、headers等嵌套的map，logfmt编码始终排序
+	SortKeys bool
+	// 是否将ctx(app.logs.v1)或extra、get、post(http.request.v1)展开为"."连接的顶层字段，
+	// 如"ctx.user.id"，对json、logfmt与cloud-logging编码生效
+	Flatten bool
+	// 容器之下最多展开的层数，小于等于0时不限制
+	FlattenDepth int
+	// 日志内容的大小限制，超出时截断
+	Limits Limits
+	// 保持ctx等容器中同名字段类型稳定的方式
+	TypeStable TypeStable
+	// 按类型注册的值转换方式，优先于内置的转换方式
+	ValueEncoders map[reflect.Type]ValueEncoder
 }
 
 // Format implements logrus.Formatter interface
 func (hf *HTTPRequestV1Formatter) Format(entry *logrus.Entry) ([]byte, error) {
-	rv, ok := entry.Data[HTTPRequestReqKey]
-	if !ok {
-		return nil, errors.New(`require "request"`)
+	if o := hf.encodeOptions(); o.streaming() && !hf.TypeStable.enabled() {
+		return hf.formatJSON(entry, o)
 	}
 
-	req, ok := rv.(*http.Request)
-	if !ok {
-		return nil, errors.Errorf(`"request" type MUST be *http.Request, got %s`, reflect.TypeOf(rv).String())
+	data, err := hf.data(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	hf.TypeStable.apply(data)
+	output, err := hf.Limits.encode(data, func() ([]byte, error) {
+		return encode(HTTPRequestV1, entry, data, hf.encodeOptions())
+	})
+	httpRequestV1Pool.Put(data)
+
+	return output, err
+}
+
+func (hf *HTTPRequestV1Formatter) encodeOptions() encodeOptions {
+	return encodeOptions{
+		encoding:            hf.Encoding,
+		ecsNamespace:        hf.ECSNamespace,
+		cloudLoggingProject: hf.CloudLoggingProject,
+		keyMap:              hf.KeyMap,
+		epoch:               validEpochUnit(hf.EpochUnit),
+		sortKeys:            hf.SortKeys,
+		flatten:             hf.Flatten,
+		flattenDepth:        hf.FlattenDepth,
+		limits:              hf.Limits.enabled(),
+	}
+}
+
+func (hf *HTTPRequestV1Formatter) extract(entry *logrus.Entry) (logData, error) {
+	return hf.data(entry)
+}
+
+// data 从日志条目中提取http.request.v1日志内容，使用完毕后应放回httpRequestV1Pool
+func (hf *HTTPRequestV1Formatter) data(entry *logrus.Entry) (*HTTPRequestV1Data, error) {
+	req, err := httpRequest(entry)
+	if err != nil {
+		return nil, err
 	}
 
 	uid := ""
@@ -164,10 +377,6 @@ func (hf *HTTPRequestV1Formatter) Format(entry *logrus.Entry) ([]byte, error) {
 		case HTTPRequestUserKey:
 			uid = fmt.Sprintf("%v", v)
 		default:
-			if err, ok := v.(error); ok {
-				extra[k] = makeErrInfo(err)
-				continue
-			}
 			extra[k] = v
 		}
 	}
@@ -175,12 +384,18 @@ func (hf *HTTPRequestV1Formatter) Format(entry *logrus.Entry) ([]byte, error) {
 	data := httpRequestV1Pool.Get().(*HTTPRequestV1Data)
 	data.Service = hf.Service
 	data.Environment = hf.Environment
-	data.Level = entry.Level.String()
-	data.Time = entry.Time.Format(hf.TimeLayout)
+	data.Metadata = hf.Metadata
+	data.Level = levelName(entry.Level)
+	data.Time, data.epoch = formatTime(entry.Time, hf.TimeLocation, hf.TimeLayout, hf.EpochUnit, hf.EpochKey)
 	data.IP = strings.Split(req.RemoteAddr, ":")[0]
 	data.Method = req.Method
 	data.Path = req.URL.Path
 	data.User = uid
+	data.truncations = data.truncations[:0]
+	data.collapsed = false
+	data.encodeErrors = data.encodeErrors[:0]
+	replaceCyclic("extra", extra, &data.encodeErrors)
+	valueEncoding{hf.ValueEncoders, hf.TimeLocation}.encodeFields(extra)
 	data.Headers = map[string]string{}
 	data.Get = logrus.Fields{}
 	data.Post = logrus.Fields{}
@@ -214,14 +429,38 @@ func (hf *HTTPRequestV1Formatter) Format(entry *logrus.Entry) ([]byte, error) {
 		}
 	}
 
-	output, err := jsoniter.Marshal(data)
-	if err != nil {
-		httpRequestV1Pool.Put(data)
-		return nil, errors.Wrapf(err, "json encode %s log", HTTPRequestV1)
+	return data, nil
+}
+
+// levelNames 各日志级别的名称，logrus.Level.String()每次调用都会分配内存
+var levelNames = func() []string {
+	names := make([]string, len(logrus.AllLevels))
+	for _, l := range logrus.AllLevels {
+		names[l] = l.String()
 	}
+	return names
+}()
 
-	httpRequestV1Pool.Put(data)
-	return append(output, '\n'), nil
+func levelName(l logrus.Level) string {
+	if int(l) < len(levelNames) {
+		return levelNames[l]
+	}
+	return l.String()
+}
+
+// httpRequest 日志条目中的请求对象
+func httpRequest(entry *logrus.Entry) (*http.Request, error) {
+	rv, ok := entry.Data[HTTPRequestReqKey]
+	if !ok {
+		return nil, errors.New(`require "request"`)
+	}
+
+	req, ok := rv.(*http.Request)
+	if !ok {
+		return nil, errors.Errorf(`"request" type MUST be *http.Request, got %s`, reflect.TypeOf(rv).String())
+	}
+
+	return req, nil
 }
 
 type stackTracer interface {
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"net/http"
@@ -29,6 +30,38 @@ func TestNewFormatter(t *testing.T) {
 	}
 }
 
+func TestNewFormatterOptions(t *testing.T) {
+	f, err := NewFormatter(APPLogsV1,
+		WithService("svc"),
+		WithEnvironment("prod"),
+		WithTimeLayout(time.RFC3339Nano),
+	)
+	if err != nil {
+		t.Fatalf("Test NewFormatter(), Expected=nil, Actual=%q", err.Error())
+	}
+
+	af, ok := f.(*APPLogsV1Formatter)
+	if !ok {
+		t.Fatalf("Test NewFormatter(), Expected=*APPLogsV1Formatter, Actual=%T", f)
+	}
+	if af.Service != "svc" || af.Environment != "prod" || af.TimeLayout != time.RFC3339Nano {
+		t.Fatalf("Test NewFormatter() options, Actual=%+v", af)
+	}
+
+	f, err = NewFormatter(HTTPRequestV1, WithService("svc"))
+	if err != nil {
+		t.Fatalf("Test NewFormatter(), Expected=nil, Actual=%q", err.Error())
+	}
+
+	hf, ok := f.(*HTTPRequestV1Formatter)
+	if !ok {
+		t.Fatalf("Test NewFormatter(), Expected=*HTTPRequestV1Formatter, Actual=%T", f)
+	}
+	if hf.Service != "svc" || hf.TimeLayout != time.RFC3339 {
+		t.Fatalf("Test NewFormatter() options, Actual=%+v", hf)
+	}
+}
+
 func TestFormatterOutput(t *testing.T) {
 	t.Run("APPLogsV1", func(t *testing.T) {
 		t.Parallel()
@@ -220,3 +253,124 @@ func TestFormatterOutput(t *testing.T) {
 		}
 	})
 }
+
+func TestFormatterSortKeys(t *testing.T) {
+	entry := &logrus.Entry{
+		Level:   logrus.InfoLevel,
+		Time:    time.Date(2019, 8, 12, 10, 13, 48, 0, time.UTC),
+		Message: "hello",
+		Data: logrus.Fields{
+			"c": 3,
+			"a": map[string]interface{}{"z": 1, "y": map[string]int{"q": 1, "p": 2}, "x": 3},
+			"b": 2,
+		},
+	}
+
+	f, _ := NewFormatter(APPLogsV1, WithSortedKeys())
+	expected := `{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","msg":"hello","ctx":{"a":{"x":3,"y":{"p":2,"q":1},"z":1},"b":2,"c":3}}` + "\n"
+	for i := 0; i < 20; i++ {
+		data, err := f.Format(entry)
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+		if string(data) != expected {
+			t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, data)
+		}
+	}
+
+	headers := http.Header{}
+	headers.Set("x-b", "1")
+	headers.Set("x-a", "2")
+	entry.Data = logrus.Fields{
+		HTTPRequestReqKey: &http.Request{
+			RemoteAddr: "1.2.3.4:1234",
+			Header:     headers,
+			Method:     http.MethodGet,
+			URL:        &url.URL{Path: "/api"},
+		},
+		"z": 1,
+		"a": 2,
+	}
+	f, _ = NewFormatter(HTTPRequestV1, WithSortedKeys(), WithEpochField("ts", time.Second))
+	expected = `{"schema":"http.request.v1","level":"info","time":"2019-08-12T10:13:48Z","ts":1565604828,"ip":"1.2.3.4","method":"GET","path":"/api","headers":{"X-A":"2","X-B":"1"},"extra":{"a":2,"z":1}}` + "\n"
+	for i := 0; i < 20; i++ {
+		data, err := f.Format(entry)
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+		if string(data) != expected {
+			t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, data)
+		}
+	}
+}
+
+func benchmarkEntry() *logrus.Entry {
+	return &logrus.Entry{
+		Level:   logrus.InfoLevel,
+		Time:    time.Now(),
+		Message: "user login failed",
+		Data: logrus.Fields{
+			ChannelKey: "auth",
+			"user_id":  123,
+			"ip":       "1.2.3.4",
+			"attempts": 3,
+			"device":   map[string]interface{}{"os": "ios", "version": "13.1", "model": "iPhone"},
+			"roles":    []string{"admin", "editor"},
+		},
+	}
+}
+
+func BenchmarkAPPLogsV1Formatter(b *testing.B) {
+	benchmarkFormatter(b, APPLogsV1, benchmarkEntry())
+}
+
+func BenchmarkHTTPRequestV1Formatter(b *testing.B) {
+	entry := benchmarkEntry()
+	entry.Data = logrus.Fields{
+		HTTPRequestReqKey: &http.Request{
+			Method:     http.MethodPost,
+			URL:        &url.URL{Path: "/api/login", RawQuery: "from=app"},
+			RemoteAddr: "1.2.3.4:5678",
+			Header:     http.Header{"User-Agent": {"ios"}, "X-Request-Id": {"abc"}},
+		},
+		HTTPRequestUserKey: "123",
+		"status":           200,
+		"latency":          "15ms",
+	}
+	benchmarkFormatter(b, HTTPRequestV1, entry)
+}
+
+// benchmarkFormatter 对比jsonEncoder与经过APPLogsV1Data等编码(ByData)的开销，
+// LogrusBuffer为通过logrus输出时写入其复用缓冲区的情况
+func benchmarkFormatter(b *testing.B, s Standard, entry *logrus.Entry) {
+	for _, c := range []struct {
+		name string
+		opts []Option
+	}{
+		{"Default", nil},
+		{"SortedKeys", []Option{WithSortedKeys()}},
+	} {
+		f, _ := NewFormatter(s, c.opts...)
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = f.Format(entry)
+			}
+		})
+		b.Run(c.name+"/LogrusBuffer", func(b *testing.B) {
+			e := *entry
+			e.Buffer = &bytes.Buffer{}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				e.Buffer.Reset()
+				_, _ = f.Format(&e)
+			}
+		})
+		b.Run(c.name+"/ByData", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = formatByData(f, entry)
+			}
+		})
+	}
+}
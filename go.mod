@@ -4,6 +4,7 @@ go 1.13
 
 require (
 	github.com/json-iterator/go v1.1.7
+	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/pkg/errors v0.8.1
 	github.com/sirupsen/logrus v1.4.2
 )
@@ -0,0 +1,503 @@
+package logger
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+// jsonEncoderPool 复用jsonEncoder及其缓冲区
+var jsonEncoderPool = sync.Pool{
+	New: func() interface{} {
+		return &jsonEncoder{}
+	},
+}
+
+// jsonEncoder 不构建中间map与结构体，直接将日志条目以json写入复用的jsoniter.Stream缓冲区，
+// 常见类型不经过反射，其余类型交给jsoniter，输出与经过APPLogsV1Data等编码的结果完全一致
+type jsonEncoder struct {
+	api      jsoniter.API
+	stream   *jsoniter.Stream
+	sortKeys bool
+	values   valueEncoding
+	// 当前值在日志内容中的路径，用于记录无法编码的值
+	path []string
+	// 排序map的key时复用的空间
+	keys []string
+	errs []encodeError
+}
+
+func newJSONEncoder(o encodeOptions, values valueEncoding) *jsonEncoder {
+	e := jsonEncoderPool.Get().(*jsonEncoder)
+	e.api = o.json()
+	e.stream = e.api.BorrowStream(nil)
+	e.sortKeys = o.sortKeys
+	e.values = values
+
+	return e
+}
+
+func (e *jsonEncoder) release() {
+	e.api.ReturnStream(e.stream)
+	e.api, e.stream = nil, nil
+	e.values = valueEncoding{}
+	e.path = e.path[:0]
+	e.keys = e.keys[:0]
+	e.errs = e.errs[:0]
+	jsonEncoderPool.Put(e)
+}
+
+// output 结束编码并返回结果，日志条目带有logrus复用的缓冲区时写入该缓冲区
+func (e *jsonEncoder) output(entry *logrus.Entry) []byte {
+	e.writeEncodeErrors()
+	e.stream.WriteObjectEnd()
+	e.stream.WriteRaw("\n")
+
+	if entry.Buffer != nil {
+		entry.Buffer.Write(e.stream.Buffer())
+		return entry.Buffer.Bytes()
+	}
+	return append([]byte(nil), e.stream.Buffer()...)
+}
+
+// writeKey 写入对象的第一个key以外的key
+func (e *jsonEncoder) writeKey(key string) {
+	e.stream.WriteMore()
+	e.stream.WriteObjectField(key)
+}
+
+func (e *jsonEncoder) writeString(s string) {
+	e.stream.WriteStringWithHTMLEscaped(s)
+}
+
+// writeTime 直接将格式化的时间写入缓冲区，需要转义时退回到普通字符串
+func (e *jsonEncoder) writeTime(t time.Time, loc *time.Location, layout string) {
+	if loc != nil {
+		t = t.In(loc)
+	}
+
+	buf := e.stream.Buffer()
+	n := len(buf)
+	buf = t.AppendFormat(append(buf, '"'), layout)
+	for _, c := range buf[n+1:] {
+		if c < 0x20 || c >= utf8.RuneSelf || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			e.stream.SetBuffer(buf[:n])
+			e.writeString(t.Format(layout))
+			return
+		}
+	}
+	e.stream.SetBuffer(append(buf, '"'))
+}
+
+// writeFields 写入ctx、extra等容器字段，skip中的key不输出
+// 与提取日志内容时一致，先替换循环引用的值，再按valueEncoding转换值
+func (e *jsonEncoder) writeFields(container string, m map[string]interface{}, skip ...string) {
+	e.path = append(e.path, container)
+	e.stream.WriteObjectStart()
+	keys := e.sortedKeys(m)
+	i := 0
+	for _, k := range keys {
+		if !skipKey(k, skip) {
+			e.writeField(i, k, m[k])
+			i++
+		}
+	}
+	if keys == nil {
+		for k, v := range m {
+			if !skipKey(k, skip) {
+				e.writeField(i, k, v)
+				i++
+			}
+		}
+	}
+	e.releaseKeys(keys)
+	e.stream.WriteObjectEnd()
+	e.path = e.path[:len(e.path)-1]
+}
+
+func skipKey(k string, skip []string) bool {
+	for _, s := range skip {
+		if k == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *jsonEncoder) writeField(i int, k string, v interface{}) {
+	e.writeObjectKey(i, k)
+	e.path = append(e.path, k)
+	if isCyclic(v) {
+		e.unencodable(v, encodeErrorCyclic)
+	} else {
+		if out, ok := e.values.encode(v); ok {
+			v = out
+		}
+		e.writeValue(v)
+	}
+	e.path = e.path[:len(e.path)-1]
+}
+
+// writeObjectKey 写入对象中第i个key，key与jsoniter编码map时一样转义html字符
+func (e *jsonEncoder) writeObjectKey(i int, k string) {
+	if i > 0 {
+		e.stream.WriteMore()
+	}
+	e.writeString(k)
+	e.stream.WriteRaw(":")
+}
+
+// sortedKeys 需要排序时返回排序后的key，否则返回nil，使用完毕后调用releaseKeys
+func (e *jsonEncoder) sortedKeys(m map[string]interface{}) []string {
+	if !e.sortKeys || len(m) < 2 {
+		return nil
+	}
+	start := len(e.keys)
+	for k := range m {
+		e.keys = append(e.keys, k)
+	}
+
+	return e.sortKeysFrom(start)
+}
+
+func (e *jsonEncoder) sortKeysFrom(start int) []string {
+	keys := e.keys[start:]
+	sort.Strings(keys)
+
+	return keys
+}
+
+// releaseKeys 嵌套的map按先进后出的顺序使用e.keys
+func (e *jsonEncoder) releaseKeys(keys []string) {
+	if keys != nil {
+		e.keys = e.keys[:len(e.keys)-len(keys)]
+	}
+}
+
+// writeValue 常见类型直接写入，其余类型交给jsoniter
+func (e *jsonEncoder) writeValue(v interface{}) {
+	s := e.stream
+	switch x := v.(type) {
+	case nil:
+		s.WriteNil()
+	case string:
+		e.writeString(x)
+	case bool:
+		s.WriteBool(x)
+	case int:
+		s.WriteInt(x)
+	case int8:
+		s.WriteInt8(x)
+	case int16:
+		s.WriteInt16(x)
+	case int32:
+		s.WriteInt32(x)
+	case int64:
+		s.WriteInt64(x)
+	case uint:
+		s.WriteUint(x)
+	case uint8:
+		s.WriteUint8(x)
+	case uint16:
+		s.WriteUint16(x)
+	case uint32:
+		s.WriteUint32(x)
+	case uint64:
+		s.WriteUint64(x)
+	case float32:
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			e.unencodable(v, fmt.Sprintf("unsupported value: %v", x))
+			return
+		}
+		s.WriteFloat32(x)
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			e.unencodable(v, fmt.Sprintf("unsupported value: %v", x))
+			return
+		}
+		s.WriteFloat64(x)
+	case logrus.Fields:
+		e.writeMap(x)
+	case map[string]interface{}:
+		e.writeMap(x)
+	case []interface{}:
+		if x == nil {
+			s.WriteNil()
+			return
+		}
+		s.WriteArrayStart()
+		for i, elem := range x {
+			if i > 0 {
+				s.WriteMore()
+			}
+			e.path = append(e.path, strconv.Itoa(i))
+			e.writeValue(elem)
+			e.path = e.path[:len(e.path)-1]
+		}
+		s.WriteArrayEnd()
+	case []string:
+		if x == nil {
+			s.WriteNil()
+			return
+		}
+		s.WriteArrayStart()
+		for i, elem := range x {
+			if i > 0 {
+				s.WriteMore()
+			}
+			e.writeString(elem)
+		}
+		s.WriteArrayEnd()
+	default:
+		e.writeReflect(v)
+	}
+}
+
+func (e *jsonEncoder) writeMap(m map[string]interface{}) {
+	if m == nil {
+		e.stream.WriteNil()
+		return
+	}
+
+	e.stream.WriteObjectStart()
+	keys := e.sortedKeys(m)
+	for i, k := range keys {
+		e.writeMapValue(i, k, m[k])
+	}
+	if keys == nil {
+		i := 0
+		for k, v := range m {
+			e.writeMapValue(i, k, v)
+			i++
+		}
+	}
+	e.releaseKeys(keys)
+	e.stream.WriteObjectEnd()
+}
+
+func (e *jsonEncoder) writeMapValue(i int, k string, v interface{}) {
+	e.writeObjectKey(i, k)
+	e.path = append(e.path, k)
+	e.writeValue(v)
+	e.path = e.path[:len(e.path)-1]
+}
+
+// writeReflect 使用jsoniter编码，编码失败时丢弃已写入的内容并替换为占位符
+func (e *jsonEncoder) writeReflect(v interface{}) {
+	s := e.stream
+	n := len(s.Buffer())
+	s.WriteVal(v)
+	if err := s.Error; err != nil {
+		s.Error = nil
+		s.SetBuffer(s.Buffer()[:n])
+		e.unencodable(v, err.Error())
+	}
+}
+
+// unencodable 写入占位符并记录无法编码的值
+func (e *jsonEncoder) unencodable(v interface{}, reason string) {
+	t, reason := describeUnencodable(v, reason)
+	e.errs = append(e.errs, encodeError{strings.Join(e.path, "."), t, reason})
+	e.writeString(encodePlaceholder(t, reason))
+}
+
+func (e *jsonEncoder) writeEncodeErrors() {
+	if len(e.errs) == 0 {
+		return
+	}
+
+	sort.Slice(e.errs, func(i, j int) bool {
+		return e.errs[i].Path < e.errs[j].Path
+	})
+	s := e.stream
+	e.writeKey(EncodeErrorsKey)
+	s.WriteArrayStart()
+	for i, err := range e.errs {
+		if i > 0 {
+			s.WriteMore()
+		}
+		s.WriteObjectStart()
+		s.WriteObjectField("key")
+		e.writeString(err.Path)
+		e.writeKey("type")
+		e.writeString(err.Type)
+		e.writeKey("error")
+		e.writeString(err.Reason)
+		s.WriteObjectEnd()
+	}
+	s.WriteArrayEnd()
+}
+
+// formatJSON 使用jsonEncoder输出app.logs.v1日志
+func (af *APPLogsV1Formatter) formatJSON(entry *logrus.Entry, o encodeOptions) ([]byte, error) {
+	e := newJSONEncoder(o, valueEncoding{af.ValueEncoders, af.TimeLocation})
+	defer e.release()
+
+	channel, _ := af.channel(entry)
+	_, hasChannel := entry.Data[ChannelKey]
+	s := e.stream
+	s.WriteObjectStart()
+	s.WriteObjectField("schema")
+	e.writeString(string(APPLogsV1))
+	if af.Service != "" {
+		e.writeKey("service")
+		e.writeString(af.Service)
+	}
+	if af.Environment != "" {
+		e.writeKey("env")
+		e.writeString(af.Environment)
+	}
+	if af.Metadata != nil {
+		e.writeKey("meta")
+		s.WriteVal(af.Metadata)
+	}
+	e.writeKey("channel")
+	e.writeString(channel)
+	e.writeKey("level")
+	e.writeString(levelName(entry.Level))
+	e.writeKey("time")
+	e.writeTime(entry.Time, af.TimeLocation, af.TimeLayout)
+	e.writeKey("msg")
+	e.writeString(entry.Message)
+	if n := len(entry.Data); n > 1 || n == 1 && !hasChannel {
+		e.writeKey("ctx")
+		e.writeFields("ctx", entry.Data, ChannelKey)
+	}
+
+	return e.output(entry), nil
+}
+
+// formatJSON 使用jsonEncoder输出http.request.v1日志
+func (hf *HTTPRequestV1Formatter) formatJSON(entry *logrus.Entry, o encodeOptions) ([]byte, error) {
+	req, err := httpRequest(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	e := newJSONEncoder(o, valueEncoding{hf.ValueEncoders, hf.TimeLocation})
+	defer e.release()
+
+	s := e.stream
+	s.WriteObjectStart()
+	s.WriteObjectField("schema")
+	e.writeString(string(HTTPRequestV1))
+	if hf.Service != "" {
+		e.writeKey("service")
+		e.writeString(hf.Service)
+	}
+	if hf.Environment != "" {
+		e.writeKey("env")
+		e.writeString(hf.Environment)
+	}
+	if hf.Metadata != nil {
+		e.writeKey("meta")
+		s.WriteVal(hf.Metadata)
+	}
+	e.writeKey("level")
+	e.writeString(levelName(entry.Level))
+	e.writeKey("time")
+	e.writeTime(entry.Time, hf.TimeLocation, hf.TimeLayout)
+	e.writeKey("ip")
+	ip := req.RemoteAddr
+	if i := strings.IndexByte(ip, ':'); i >= 0 {
+		ip = ip[:i]
+	}
+	e.writeString(ip)
+	e.writeKey("method")
+	e.writeString(req.Method)
+	e.writeKey("path")
+	e.writeString(req.URL.Path)
+
+	extra := 0
+	for k, v := range entry.Data {
+		switch k {
+		case HTTPRequestReqKey:
+		case HTTPRequestUserKey:
+			uid, ok := v.(string)
+			if !ok {
+				uid = fmt.Sprintf("%v", v)
+			}
+			if uid != "" {
+				e.writeKey("user")
+				e.writeString(uid)
+			}
+		default:
+			extra++
+		}
+	}
+
+	if len(req.Header) > 0 {
+		e.writeKey("headers")
+		e.writeHeader(req.Header)
+	}
+	if req.URL.RawQuery != "" {
+		if q := req.URL.Query(); len(q) > 0 {
+			e.writeKey("get")
+			e.writeForm(q)
+		}
+	}
+	if len(req.PostForm) > 0 {
+		e.writeKey("post")
+		e.writeForm(req.PostForm)
+	}
+	if extra > 0 {
+		e.writeKey("extra")
+		e.writeFields("extra", entry.Data, HTTPRequestReqKey, HTTPRequestUserKey)
+	}
+
+	return e.output(entry), nil
+}
+
+// writeHeader 多个值以", "连接
+func (e *jsonEncoder) writeHeader(h http.Header) {
+	e.writeStrings(h, true)
+}
+
+// writeForm 只有一个值时输出字符串，否则输出数组
+func (e *jsonEncoder) writeForm(form map[string][]string) {
+	e.writeStrings(form, false)
+}
+
+func (e *jsonEncoder) writeStrings(m map[string][]string, join bool) {
+	e.stream.WriteObjectStart()
+	if e.sortKeys && len(m) > 1 {
+		start := len(e.keys)
+		for k := range m {
+			e.keys = append(e.keys, k)
+		}
+		keys := e.sortKeysFrom(start)
+		for i, k := range keys {
+			e.writeStringsValue(i, k, m[k], join)
+		}
+		e.releaseKeys(keys)
+	} else {
+		i := 0
+		for k, v := range m {
+			e.writeStringsValue(i, k, v, join)
+			i++
+		}
+	}
+	e.stream.WriteObjectEnd()
+}
+
+func (e *jsonEncoder) writeStringsValue(i int, k string, v []string, join bool) {
+	e.writeObjectKey(i, k)
+	switch {
+	case len(v) == 1:
+		e.writeString(v[0])
+	case join:
+		e.writeString(strings.Join(v, ", "))
+	default:
+		e.writeValue(v)
+	}
+}
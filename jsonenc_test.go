@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// formatByData 经过APPLogsV1Data等编码日志条目，作为jsonEncoder输出的参照
+func formatByData(f logrus.Formatter, entry *logrus.Entry) ([]byte, error) {
+	switch f := f.(type) {
+	case *APPLogsV1Formatter:
+		data := f.data(entry)
+		defer data.release()
+		return encode(APPLogsV1, entry, data, f.encodeOptions())
+	case *HTTPRequestV1Formatter:
+		data, err := f.data(entry)
+		if err != nil {
+			return nil, err
+		}
+		defer data.release()
+		return encode(HTTPRequestV1, entry, data, f.encodeOptions())
+	}
+
+	return nil, nil
+}
+
+type jsonEncTestStruct struct {
+	Name  string            `json:"name"`
+	Tags  map[string]string `json:"tags"`
+	Inner *jsonEncTestStruct
+}
+
+func TestJSONEncoder(t *testing.T) {
+	now := time.Date(2019, 8, 12, 10, 13, 48, 123456789, time.UTC)
+	self := map[string]interface{}{}
+	self["self"] = self
+
+	appEntries := []logrus.Fields{
+		{},
+		{ChannelKey: "auth"},
+		{ChannelKey: 1},
+		{
+			"str":     "<a href=\"x\">&</a>\n\t\x01 中文\xff",
+			"<key>":   true,
+			"ints":    []interface{}{1, int8(-2), int16(3), int32(4), int64(-5), uint(6), uint8(7), uint16(8), uint32(9), uint64(10)},
+			"floats":  []interface{}{1.5, float32(0.1), 1e21, 1e-7, -0.0, 100.0},
+			"nil":     nil,
+			"strs":    []string{"a", "<b>"},
+			"nilstrs": []string(nil),
+			"nilmap":  logrus.Fields(nil),
+			"nested":  map[string]interface{}{"b": map[string]interface{}{"y": 1, "x": []interface{}{map[string]interface{}{"q": 1, "p": 2}}}, "a": nil},
+		},
+		{
+			logrus.ErrorKey: errors.New("failed"),
+			"wait":          1500 * time.Millisecond,
+			"at":            now,
+			"raw":           []byte("raw"),
+			"struct":        jsonEncTestStruct{Name: "s", Tags: map[string]string{"z": "1", "a": "2"}, Inner: &jsonEncTestStruct{Name: "inner"}},
+			"ptr":           (*jsonEncTestStruct)(nil),
+			"ints":          []int{1, 2},
+			"m":             map[string]int{"b": 1, "a": 2},
+		},
+		{
+			"nan":    math.NaN(),
+			"inf":    float32(math.Inf(-1)),
+			"ch":     make(chan int),
+			"self":   self,
+			"nested": map[string]interface{}{"fn": func() {}, "ok": 1, "list": []interface{}{math.NaN(), "x"}},
+		},
+	}
+
+	formatters := map[string][]Option{
+		"Default":    {WithSortedKeys()},
+		"Metadata":   {WithSortedKeys(), WithService("svc"), WithEnvironment("prod"), WithMetadata(&Metadata{Host: "h", PID: 1, Version: "v1"})},
+		"Location":   {WithSortedKeys(), WithTimeLocation(time.FixedZone("CST", 8*3600)), WithTimeLayout(time.RFC3339Nano)},
+		"EscapeTime": {WithSortedKeys(), WithTimeLayout(`2006-01-02 "15:04" <MST> 中`)},
+	}
+
+	for name, opts := range formatters {
+		f, _ := NewFormatter(APPLogsV1, opts...)
+		for i, data := range appEntries {
+			entry := &logrus.Entry{Level: logrus.WarnLevel, Time: now, Message: "hello <world>", Data: data}
+			expected, err := formatByData(f, entry)
+			if err != nil {
+				t.Fatalf("%s[%d] formatByData() error, Expected=nil, Actual=%q", name, i, err.Error())
+			}
+			actual, err := f.Format(entry)
+			if err != nil {
+				t.Fatalf("%s[%d] Format() error, Expected=nil, Actual=%q", name, i, err.Error())
+			}
+			if !bytes.Equal(actual, expected) {
+				t.Fatalf("%s[%d] Format() output, Expected=%q, Actual=%q", name, i, expected, actual)
+			}
+		}
+	}
+
+	req := &http.Request{
+		Method:     http.MethodPost,
+		URL:        &url.URL{Path: "/api/<x>", RawQuery: "b=1&a=2&a=3"},
+		RemoteAddr: "1.2.3.4:5678",
+		Header:     http.Header{"X-B": {"1"}, "X-A": {"2", "3"}},
+		PostForm:   url.Values{"p": {"1"}, "q": {"1", "2"}},
+	}
+	httpEntries := []logrus.Fields{
+		{HTTPRequestReqKey: &http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/"}, RemoteAddr: "[::1]"}},
+		{HTTPRequestReqKey: req, HTTPRequestUserKey: 123, "status": 200, "latency": time.Second, "err": errors.New("x"), "self": self},
+		{HTTPRequestReqKey: req, HTTPRequestUserKey: ""},
+	}
+	for name, opts := range formatters {
+		f, _ := NewFormatter(HTTPRequestV1, opts...)
+		for i, data := range httpEntries {
+			entry := &logrus.Entry{Level: logrus.InfoLevel, Time: now, Data: data}
+			expected, err := formatByData(f, entry)
+			if err != nil {
+				t.Fatalf("%s[%d] formatByData() error, Expected=nil, Actual=%q", name, i, err.Error())
+			}
+			actual, err := f.Format(entry)
+			if err != nil {
+				t.Fatalf("%s[%d] Format() error, Expected=nil, Actual=%q", name, i, err.Error())
+			}
+			if !bytes.Equal(actual, expected) {
+				t.Fatalf("%s[%d] Format() output, Expected=%q, Actual=%q", name, i, expected, actual)
+			}
+		}
+	}
+
+	// 使用logrus复用的缓冲区
+	f, _ := NewFormatter(APPLogsV1)
+	entry := &logrus.Entry{Level: logrus.InfoLevel, Time: now, Message: "hello", Buffer: &bytes.Buffer{}}
+	data, _ := f.Format(entry)
+	if !bytes.Equal(data, entry.Buffer.Bytes()) {
+		t.Fatalf("Format() output, Expected=%q, Actual=%q", entry.Buffer.Bytes(), data)
+	}
+	if _, err := f.(*APPLogsV1Formatter).Format(&logrus.Entry{Data: logrus.Fields{}}); err != nil {
+		t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+	}
+
+	hf, _ := NewFormatter(HTTPRequestV1)
+	if _, err := hf.Format(&logrus.Entry{Data: logrus.Fields{}}); err == nil {
+		t.Fatal(`Format() without "request" error, Expected=error, Actual=nil`)
+	}
+}
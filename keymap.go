@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInvalidKeyMap 字段名映射不合法
+	ErrInvalidKeyMap = errors.New("invalid log key map")
+
+	// standardKeys 各日志规范输出的顶层字段名
+	standardKeys = map[Standard][]string{
+		APPLogsV1:     jsonKeys(reflect.TypeOf(APPLogsV1Data{})),
+		HTTPRequestV1: jsonKeys(reflect.TypeOf(HTTPRequestV1Data{})),
+	}
+
+	// reservedKeys 编码时可能追加的顶层字段名，不能作为映射后的字段名
+	reservedKeys = []string{"truncated", "truncations", "truncation_count", EncodeErrorsKey}
+
+	// encodingReservedKeys 各编码方式额外追加的顶层字段名
+	encodingReservedKeys = map[Encoding][]string{
+		EncodingCloudLogging: {
+			"severity",
+			"httpRequest",
+			cloudLoggingTraceKey,
+			cloudLoggingSpanIDKey,
+			cloudLoggingSourceLocationKey,
+		},
+	}
+)
+
+// ValidateKeyMap 校验日志规范的字段名映射
+// 只能映射规范中已有的顶层字段，映射后的字段名不能为空，且不能与其他字段或truncated等编码时追加的字段重名
+func ValidateKeyMap(s Standard, m map[string]string) error {
+	return validateKeys(s, m, "", "")
+}
+
+// validateKeys 校验字段名映射，extra为额外输出的顶层字段名(如Unix时间戳字段)，为空时忽略，
+// enc为编码方式，映射后的字段名不能与该编码方式追加的字段重名
+func validateKeys(s Standard, m map[string]string, extra string, enc Encoding) error {
+	keys, ok := standardKeys[s]
+	if !ok {
+		return errors.Wrapf(ErrFormatterNotFound, "log standard %q", s)
+	}
+
+	known := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		known[k] = true
+	}
+
+	seen := make(map[string]string, len(keys))
+	for from, to := range m {
+		if !known[from] {
+			return errors.Wrapf(ErrInvalidKeyMap, "%s has no key %q", s, from)
+		}
+		if to == "" {
+			return errors.Wrapf(ErrInvalidKeyMap, "key %q mapped to empty name", from)
+		}
+	}
+	for _, k := range keys {
+		to := k
+		if v, ok := m[k]; ok {
+			to = v
+		}
+		if other, ok := seen[to]; ok {
+			return errors.Wrapf(ErrInvalidKeyMap, "keys %q and %q both output as %q", other, k, to)
+		}
+		seen[to] = k
+	}
+	if other, ok := seen[extra]; ok && extra != "" {
+		return errors.Wrapf(ErrInvalidKeyMap, "keys %q and %q both output as %q", other, extra, extra)
+	}
+	if extra != "" {
+		seen[extra] = extra
+	}
+	for _, r := range append(reservedKeys[:len(reservedKeys):len(reservedKeys)], encodingReservedKeys[enc]...) {
+		if other, ok := seen[r]; ok {
+			return errors.Wrapf(ErrInvalidKeyMap, "key %q output as reserved key %q", other, r)
+		}
+	}
+
+	return nil
+}
+
+// checkDuplicateKeys 检查重命名或展开后是否出现重名字段
+func checkDuplicateKeys(fs []field) error {
+	seen := make(map[string]bool, len(fs))
+	for _, f := range fs {
+		if seen[f.Key] {
+			return errors.Wrapf(ErrInvalidKeyMap, "duplicate key %q", f.Key)
+		}
+		seen[f.Key] = true
+	}
+
+	return nil
+}
+
+// jsonKeys 结构体各字段json tag中的字段名
+func jsonKeys(t reflect.Type) []string {
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+
+	return keys
+}
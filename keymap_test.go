@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func TestValidateKeyMap(t *testing.T) {
+	cases := []struct {
+		standard Standard
+		keyMap   map[string]string
+		valid    bool
+	}{
+		{standard: APPLogsV1, keyMap: map[string]string{"msg": "message", "time": "@timestamp"}, valid: true},
+		{standard: APPLogsV1, keyMap: map[string]string{"msg": "time", "time": "msg"}, valid: true},
+		{standard: HTTPRequestV1, keyMap: map[string]string{"extra": "ext", "headers": "h"}, valid: true},
+		{standard: APPLogsV1, keyMap: map[string]string{"msg": "time"}, valid: false},
+		{standard: APPLogsV1, keyMap: map[string]string{"msg": "m", "level": "m"}, valid: false},
+		{standard: APPLogsV1, keyMap: map[string]string{"extra": "ext"}, valid: false},
+		{standard: APPLogsV1, keyMap: map[string]string{"msg": ""}, valid: false},
+		{standard: APPLogsV1, keyMap: map[string]string{"msg": "truncated"}, valid: false},
+		{standard: HTTPRequestV1, keyMap: map[string]string{"extra": "truncations"}, valid: false},
+		{standard: APPLogsV1, keyMap: map[string]string{"ctx": EncodeErrorsKey}, valid: false},
+		{standard: "undefined", keyMap: map[string]string{}, valid: false},
+	}
+
+	for i, c := range cases {
+		err := ValidateKeyMap(c.standard, c.keyMap)
+		if c.valid && err != nil {
+			t.Fatalf("Test ValidateKeyMap() case %d, Expected=nil, Actual=%q", i, err.Error())
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("Test ValidateKeyMap() case %d, Expected error, Actual=nil", i)
+		}
+	}
+
+	if _, err := NewFormatter(APPLogsV1, WithKeyMap(map[string]string{"msg": "level"})); errors.Cause(err) != ErrInvalidKeyMap {
+		t.Fatalf("Test NewFormatter(), Expected=%q, Actual=%v", ErrInvalidKeyMap, err)
+	}
+
+	// 不能与编码方式追加的字段重名
+	cloudLogging := map[string]string{"level": "severity"}
+	if _, err := NewFormatter(APPLogsV1, WithKeyMap(cloudLogging)); err != nil {
+		t.Fatalf("Test NewFormatter() json, Expected=nil, Actual=%q", err.Error())
+	}
+	if _, err := NewFormatter(APPLogsV1, WithKeyMap(cloudLogging), WithEncoding(EncodingCloudLogging)); errors.Cause(err) != ErrInvalidKeyMap {
+		t.Fatalf("Test NewFormatter() cloud logging, Expected=%q, Actual=%v", ErrInvalidKeyMap, err)
+	}
+	// ECS与OTLP编码不能重命名字段
+	for _, enc := range []Encoding{EncodingECS, EncodingOTLP} {
+		if _, err := NewFormatter(APPLogsV1, WithKeyMap(map[string]string{"msg": "message"}), WithEncoding(enc)); errors.Cause(err) != ErrOptionNotSupported {
+			t.Fatalf("Test NewFormatter() %s, Expected=%q, Actual=%v", enc, ErrOptionNotSupported, err)
+		}
+	}
+	if _, err := NewFormatter(APPLogsV1, WithEpochField("truncated", time.Millisecond)); errors.Cause(err) != ErrInvalidKeyMap {
+		t.Fatalf("Test NewFormatter() epoch key, Expected=%q, Actual=%v", ErrInvalidKeyMap, err)
+	}
+}
+
+func TestKeyMapOutput(t *testing.T) {
+	entry := &logrus.Entry{
+		Level:   logrus.InfoLevel,
+		Time:    time.Date(2019, 8, 12, 10, 13, 48, 0, time.UTC),
+		Message: "hello",
+		Data:    logrus.Fields{"foo": "bar"},
+	}
+	keyMap := map[string]string{"msg": "message", "time": "@timestamp", "ctx": "context"}
+
+	f, err := NewFormatter(APPLogsV1, WithKeyMap(keyMap))
+	if err != nil {
+		t.Fatalf("Test NewFormatter(), Expected=nil, Actual=%q", err.Error())
+	}
+	data, err := f.Format(entry)
+	if err != nil {
+		t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+	}
+
+	expected := `{"schema":"app.logs.v1","channel":"","level":"info","@timestamp":"2019-08-12T10:13:48Z","message":"hello","context":{"foo":"bar"}}` + "\n"
+	if string(data) != expected {
+		t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, data)
+	}
+
+	f, _ = NewFormatter(APPLogsV1, WithKeyMap(keyMap), WithEncoding(EncodingLogfmt))
+	data, err = f.Format(entry)
+	if err != nil {
+		t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+	}
+	expected = `schema=app.logs.v1 channel="" level=info @timestamp=2019-08-12T10:13:48Z message=hello context.foo=bar` + "\n"
+	if string(data) != expected {
+		t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, data)
+	}
+
+	// 未经校验的映射在Format时产生重名字段应返回错误
+	af := &APPLogsV1Formatter{KeyMap: map[string]string{"msg": "level"}}
+	if _, err := af.Format(entry); errors.Cause(err) != ErrInvalidKeyMap {
+		t.Fatalf("Format() error, Expected=%q, Actual=%v", ErrInvalidKeyMap, err)
+	}
+
+	hf := &HTTPRequestV1Formatter{KeyMap: map[string]string{"extra": "ext"}}
+	entry.Data = logrus.Fields{HTTPRequestReqKey: &http.Request{RemoteAddr: "1.2.3.4:1234", Method: http.MethodGet, URL: &url.URL{Path: "/"}}, "status": 200}
+	data, err = hf.Format(entry)
+	if err != nil {
+		t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+	}
+	if v := jsoniter.Get(data, "ext", "status").ToInt(); v != 200 {
+		t.Fatalf(`Format() output "ext.status", Expected=200, Actual=%d`, v)
+	}
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// LazyValue 延迟计算的字段值，只在日志被格式化输出时计算，因日志级别等原因未输出的日志不会计算
+// 计算时panic不会影响日志输出，该字段会被替换为占位符并记录在_encode_errors中
+type LazyValue struct {
+	fn func() interface{}
+}
+
+// Lazy 创建延迟计算的字段值，如log.WithField("diff", logger.Lazy(func() interface{} { return diff(a, b) })).Debug("...")
+// logrus不接受func类型的字段值，因此需要包装为LazyValue
+func Lazy(fn func() interface{}) LazyValue {
+	return LazyValue{fn}
+}
+
+// evaluate 计算并捕获panic
+func (l LazyValue) evaluate() (v interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("lazy value panic: %v", r)
+		}
+	}()
+
+	return l.fn(), nil
+}
+
+// encodeFailure 编码时必定失败的值，用于将无法得到的值交给_encode_errors处理
+type encodeFailure struct {
+	typ    string
+	reason string
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (f encodeFailure) MarshalJSON() ([]byte, error) {
+	return nil, errors.New(f.reason)
+}
+
+// lazyValue 计算延迟的值，失败或结果存在循环引用时返回encodeFailure
+func (ve valueEncoding) lazyValue(l LazyValue) interface{} {
+	v, err := l.evaluate()
+	if err != nil {
+		return encodeFailure{reflect.TypeOf(l).String(), err.Error()}
+	}
+	if isCyclic(v) {
+		return encodeFailure{reflect.TypeOf(v).String(), encodeErrorCyclic}
+	}
+	if out, ok := ve.encode(v); ok {
+		return out
+	}
+
+	return v
+}
+
+// describeUnencodable 无法编码的值的类型名称与原因，encodeFailure使用其记录的类型与原因
+func describeUnencodable(v interface{}, reason string) (string, string) {
+	if f, ok := v.(encodeFailure); ok {
+		return f.typ, f.reason
+	}
+
+	return fmt.Sprintf("%T", v), reason
+}
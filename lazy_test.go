@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLazy(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log, _ := NewLogger(APPLogsV1, WithOutput(buf), WithLevel(logrus.InfoLevel), WithSortedKeys())
+
+	calls := 0
+	value := Lazy(func() interface{} {
+		calls++
+		return map[string]interface{}{"wait": time.Second}
+	})
+
+	log.WithField("diff", value).Debug("disabled")
+	if calls != 0 || buf.Len() != 0 {
+		t.Fatalf("Lazy calls of disabled entry, Expected=0, Actual=%d", calls)
+	}
+
+	log.WithFields(logrus.Fields{
+		"diff":   value,
+		"err":    Lazy(func() interface{} { return errors.New("failed") }),
+		"nested": map[string]interface{}{"n": Lazy(func() interface{} { return 1 })},
+	}).Info("enabled")
+	if calls != 1 {
+		t.Fatalf("Lazy calls, Expected=1, Actual=%d", calls)
+	}
+	data := buf.Bytes()
+	cases := []struct {
+		path     []interface{}
+		expected string
+	}{
+		{path: []interface{}{"ctx", "diff", "wait"}, expected: "1s"},
+		{path: []interface{}{"ctx", "err", "msg"}, expected: "failed"},
+		{path: []interface{}{"ctx", "nested", "n"}, expected: "1"},
+	}
+	for _, c := range cases {
+		if actual := jsoniter.Get(data, c.path...).ToString(); actual != c.expected {
+			t.Fatalf("Format() output %v, Expected=%q, Actual=%q", c.path, c.expected, actual)
+		}
+	}
+
+	// 计算时panic
+	now := time.Date(2019, 8, 12, 10, 13, 48, 0, time.UTC)
+	entry := &logrus.Entry{
+		Level:   logrus.InfoLevel,
+		Time:    now,
+		Message: "hello",
+		Data: logrus.Fields{
+			"bad": Lazy(func() interface{} { panic("boom") }),
+		},
+	}
+	expected := map[Encoding]string{
+		EncodingJSON: `{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","msg":"hello",` +
+			`"ctx":{"bad":"!unencodable(logger.LazyValue: lazy value panic: boom)"},` +
+			`"_encode_errors":[{"key":"ctx.bad","type":"logger.LazyValue","error":"lazy value panic: boom"}]}` + "\n",
+		EncodingLogfmt: `schema=app.logs.v1 channel="" level=info time=2019-08-12T10:13:48Z msg=hello ` +
+			`ctx.bad="!unencodable(logger.LazyValue: lazy value panic: boom)" ` +
+			`_encode_errors.0.key=ctx.bad _encode_errors.0.type=logger.LazyValue _encode_errors.0.error="lazy value panic: boom"` + "\n",
+	}
+	for enc, e := range expected {
+		f, _ := NewFormatter(APPLogsV1, WithEncoding(enc))
+		data, err := f.Format(entry)
+		if err != nil {
+			t.Fatalf("%s Format() error, Expected=nil, Actual=%q", enc, err.Error())
+		}
+		if string(data) != e {
+			t.Fatalf("%s Format() output, Expected=%q, Actual=%q", enc, e, data)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package logger
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 截断原因
+const (
+	TruncateReasonLength = "length"
+	TruncateReasonKeys   = "keys"
+	TruncateReasonDepth  = "depth"
+	TruncateReasonLine   = "line"
+)
+
+// Limits 日志内容的大小限制，各项小于等于0时不限制
+// 超出限制的内容会被截断而不是返回错误，同时输出"truncated": true，
+// 以及"truncations"记录每处截断的位置(path)、原因(reason)和原始长度(length)，
+// 截断记录本身超出单行限制时改为输出"truncation_count"记录截断的次数
+type Limits struct {
+	// msg最大字节数
+	MaxMessageLength int
+	// ctx、extra、headers、get、post中字符串值的最大字节数
+	MaxStringLength int
+	// ctx、extra等及其嵌套的每个map最多保留的key数量，按key排序保留
+	MaxKeys int
+	// ctx、extra等之下最多嵌套的层数，超出的map与数组输出为null
+	MaxDepth int
+	// 单行日志最大字节数，超出时依次丢弃ctx、extra等嵌套内容，截断msg(http.request.v1为path)，
+	// 仍然超出时将truncations替换为truncation_count。schema、meta等固定字段不会被截断
+	MaxLineBytes int
+}
+
+// truncation 截断记录
+type truncation struct {
+	Path   string
+	Reason string
+	Length int
+}
+
+// truncationFields 存在截断记录时在字段末尾追加truncated与truncations，collapsed时只追加截断的次数
+func truncationFields(fs []field, ts []truncation, collapsed bool) []field {
+	if len(ts) == 0 {
+		return fs
+	}
+	if collapsed {
+		return append(fs, field{"truncated", true}, field{"truncation_count", len(ts)})
+	}
+
+	list := make([]interface{}, len(ts))
+	for i, t := range ts {
+		item := []field{{"path", t.Path}, {"reason", t.Reason}}
+		if t.Length > 0 {
+			item = append(item, field{"length", t.Length})
+		}
+		list[i] = item
+	}
+
+	return append(fs, field{"truncated", true}, field{"truncations", list})
+}
+
+func (l Limits) enabled() bool {
+	return l.MaxMessageLength > 0 || l.MaxStringLength > 0 || l.MaxKeys > 0 || l.MaxDepth > 0 || l.MaxLineBytes > 0
+}
+
+// encode 按限制截断日志内容后编码，编码结果超出单行限制时逐步丢弃内容重新编码
+func (l Limits) encode(data logData, enc func() ([]byte, error)) ([]byte, error) {
+	if !l.enabled() {
+		return enc()
+	}
+
+	l.apply(data)
+	output, err := enc()
+	if err != nil || l.MaxLineBytes <= 0 || len(output) <= l.MaxLineBytes {
+		return output, err
+	}
+
+	// 丢弃嵌套内容
+	length := len(output)
+	switch d := data.(type) {
+	case *APPLogsV1Data:
+		if len(d.Context) > 0 {
+			d.truncations = append(d.truncations, truncation{"ctx", TruncateReasonLine, length})
+			d.Context = nil
+		}
+	case *HTTPRequestV1Data:
+		for _, c := range []struct {
+			path string
+			n    int
+		}{{"headers", len(d.Headers)}, {"get", len(d.Get)}, {"post", len(d.Post)}, {"extra", len(d.Extra)}} {
+			if c.n > 0 {
+				d.truncations = append(d.truncations, truncation{c.path, TruncateReasonLine, length})
+			}
+		}
+		d.Headers, d.Get, d.Post, d.Extra = nil, nil, nil, nil
+	}
+	if output, err = enc(); err != nil || len(output) <= l.MaxLineBytes {
+		return output, err
+	}
+
+	// 截断msg或path，转义可能使编码后的长度大于原始长度，因此需要多次尝试
+	var s *string
+	var ts *[]truncation
+	var collapsed *bool
+	path := ""
+	switch d := data.(type) {
+	case *APPLogsV1Data:
+		s, ts, collapsed, path = &d.Message, &d.truncations, &d.collapsed, "msg"
+	case *HTTPRequestV1Data:
+		s, ts, collapsed, path = &d.Path, &d.truncations, &d.collapsed, "path"
+	default:
+		return output, nil
+	}
+	*ts = append(*ts, truncation{path, TruncateReasonLine, len(*s)})
+	original := *s
+	if output, err = l.truncateLine(s, output, enc); err != nil || len(output) <= l.MaxLineBytes {
+		return output, err
+	}
+
+	// 截断记录本身放不下时只输出截断的次数，并重新截断msg或path
+	*collapsed = true
+	*s = original
+	if output, err = enc(); err != nil {
+		return nil, err
+	}
+
+	return l.truncateLine(s, output, enc)
+}
+
+// truncateLine 截断s直到编码结果不超出单行限制或s为空
+func (l Limits) truncateLine(s *string, output []byte, enc func() ([]byte, error)) ([]byte, error) {
+	var err error
+	for i := 0; len(output) > l.MaxLineBytes && len(*s) > 0; i++ {
+		keep := len(*s) - (len(output) - l.MaxLineBytes)
+		if i >= 3 || keep < 0 {
+			keep = 0
+		}
+		*s = truncateString(*s, keep)
+		if output, err = enc(); err != nil {
+			return nil, err
+		}
+	}
+
+	return output, nil
+}
+
+// apply 截断日志内容中超出限制的部分
+func (l Limits) apply(data logData) {
+	switch d := data.(type) {
+	case *APPLogsV1Data:
+		d.Message = l.limitString("msg", d.Message, l.MaxMessageLength, &d.truncations)
+		if len(d.Context) > 0 {
+			d.Context = l.limitMap("ctx", d.Context, 0, &d.truncations)
+		}
+	case *HTTPRequestV1Data:
+		if l.MaxStringLength > 0 {
+			for k, v := range d.Headers {
+				d.Headers[k] = l.limitString("headers."+k, v, l.MaxStringLength, &d.truncations)
+			}
+		}
+		if len(d.Get) > 0 {
+			d.Get = l.limitMap("get", d.Get, 0, &d.truncations)
+		}
+		if len(d.Post) > 0 {
+			d.Post = l.limitMap("post", d.Post, 0, &d.truncations)
+		}
+		if len(d.Extra) > 0 {
+			d.Extra = l.limitMap("extra", d.Extra, 0, &d.truncations)
+		}
+	}
+}
+
+func (l Limits) limitString(path, s string, max int, ts *[]truncation) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+
+	*ts = append(*ts, truncation{path, TruncateReasonLength, len(s)})
+	return truncateString(s, max)
+}
+
+// limitMap 返回截断后的新map，不修改原有的map；depth为m所在的层数，ctx、extra等为0
+func (l Limits) limitMap(path string, m map[string]interface{}, depth int, ts *[]truncation) map[string]interface{} {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if l.MaxKeys > 0 && len(keys) > l.MaxKeys {
+		*ts = append(*ts, truncation{path, TruncateReasonKeys, len(keys)})
+		keys = keys[:l.MaxKeys]
+	}
+
+	out := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		out[k] = l.limitValue(path+"."+k, m[k], depth+1, ts)
+	}
+
+	return out
+}
+
+// limitValue 截断值中超出限制的部分，depth为v所在的层数
+func (l Limits) limitValue(path string, v interface{}, depth int, ts *[]truncation) interface{} {
+	switch x := v.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case string:
+		return l.limitString(path, x, l.MaxStringLength, ts)
+	}
+
+	// makeErrInfo生成的错误信息不受层数限制
+	if _, _, ok := errInfo(v); ok {
+		return l.limitMap(path, v.(logrus.Fields), depth-1, ts)
+	}
+
+	if m, ok := stringMap(v); ok {
+		if l.MaxDepth > 0 && depth >= l.MaxDepth {
+			*ts = append(*ts, truncation{path, TruncateReasonDepth, 0})
+			return nil
+		}
+		return l.limitMap(path, m, depth, ts)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return v
+		}
+		if l.MaxDepth > 0 && depth >= l.MaxDepth {
+			*ts = append(*ts, truncation{path, TruncateReasonDepth, 0})
+			return nil
+		}
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = l.limitValue(path+"."+strconv.Itoa(i), rv.Index(i).Interface(), depth+1, ts)
+		}
+		return out
+	case reflect.Struct, reflect.Ptr:
+		// 结构体按json规则转换为基础类型后再截断
+		if plain, err := toPlain(v); err == nil && plain != nil {
+			if _, ok := plain.(map[string]interface{}); ok {
+				return l.limitValue(path, plain, depth, ts)
+			}
+		}
+	}
+
+	return v
+}
+
+// truncateString 截断为最多max字节，不会截断在多字节字符中间
+func truncateString(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+
+	return s[:max]
+}
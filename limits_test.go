@@ -0,0 +1,182 @@
+package logger
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLimits(t *testing.T) {
+	now := time.Date(2019, 8, 12, 10, 13, 48, 0, time.UTC)
+
+	t.Run("Fields", func(t *testing.T) {
+		nested := map[string]interface{}{"l2": map[string]interface{}{"l3": "deep"}}
+		f, _ := NewFormatter(APPLogsV1, WithSortedKeys(), WithLimits(Limits{
+			MaxMessageLength: 5,
+			MaxStringLength:  3,
+			MaxKeys:          3,
+			MaxDepth:         2,
+		}))
+		data, err := f.Format(&logrus.Entry{
+			Level:   logrus.InfoLevel,
+			Time:    now,
+			Message: "hello world",
+			Data: logrus.Fields{
+				"a": "abcdef",
+				"b": nested,
+				"c": []string{"x", "yyyy"},
+				"d": 1,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		expected := `{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","msg":"hello",` +
+			`"ctx":{"a":"abc","b":{"l2":null},"c":["x","yyy"]},"truncated":true,"truncations":[` +
+			`{"path":"msg","reason":"length","length":11},` +
+			`{"path":"ctx","reason":"keys","length":4},` +
+			`{"path":"ctx.a","reason":"length","length":6},` +
+			`{"path":"ctx.b.l2","reason":"depth"},` +
+			`{"path":"ctx.c.1","reason":"length","length":4}]}` + "\n"
+		if string(data) != expected {
+			t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, data)
+		}
+
+		// 原有的值不应被修改
+		if _, ok := nested["l2"].(map[string]interface{}); !ok {
+			t.Fatal("Format() modified original ctx value")
+		}
+	})
+
+	t.Run("MaxLineBytes", func(t *testing.T) {
+		f, _ := NewFormatter(APPLogsV1, WithLimits(Limits{MaxLineBytes: 300}))
+		entry := &logrus.Entry{
+			Level:   logrus.InfoLevel,
+			Time:    now,
+			Message: "hello",
+			Data:    logrus.Fields{"big": strings.Repeat("x", 1000)},
+		}
+		data, err := f.Format(entry)
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+		if len(data) > 300 {
+			t.Fatalf("Format() output length, Expected<=300, Actual=%d", len(data))
+		}
+		if v := jsoniter.Get(data, "msg").ToString(); v != "hello" {
+			t.Fatalf(`Format() output "msg", Expected=%q, Actual=%q`, "hello", v)
+		}
+		if v := jsoniter.Get(data, "truncations", 0, "path").ToString(); v != "ctx" {
+			t.Fatalf(`Format() output "truncations.0.path", Expected=%q, Actual=%q`, "ctx", v)
+		}
+
+		entry.Message = strings.Repeat("中\"", 1000)
+		data, err = f.Format(entry)
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+		if len(data) > 300 {
+			t.Fatalf("Format() output length, Expected<=300, Actual=%d", len(data))
+		}
+		if !jsoniter.Valid(data) {
+			t.Fatalf("Format() output, Expected valid json, Actual=%q", data)
+		}
+		if v := jsoniter.Get(data, "truncated").ToBool(); !v {
+			t.Fatalf(`Format() output "truncated", Expected=true, Actual=%v`, v)
+		}
+	})
+
+	t.Run("CollapsedTruncations", func(t *testing.T) {
+		// 截断记录本身放不下时只输出截断次数
+		f, _ := NewFormatter(APPLogsV1, WithLimits(Limits{MaxLineBytes: 200, MaxStringLength: 10}))
+		data, err := f.Format(&logrus.Entry{
+			Level:   logrus.InfoLevel,
+			Time:    now,
+			Message: strings.Repeat("x", 10240),
+			Data:    logrus.Fields{"a": strings.Repeat("a", 20)},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+		if len(data) > 200 {
+			t.Fatalf("Format() output length, Expected<=200, Actual=%d (%q)", len(data), data)
+		}
+		if v := jsoniter.Get(data, "truncation_count").ToInt(); v != 3 {
+			t.Fatalf(`Format() output "truncation_count", Expected=3, Actual=%d`, v)
+		}
+		if v := jsoniter.Get(data, "msg").ToString(); v == "" {
+			t.Fatal(`Format() output "msg", Expected not empty`)
+		}
+	})
+
+	t.Run("ECS", func(t *testing.T) {
+		f, _ := NewFormatter(APPLogsV1, WithEncoding(EncodingECS), WithLimits(Limits{MaxMessageLength: 5}))
+		data, err := f.Format(&logrus.Entry{Level: logrus.InfoLevel, Time: now, Message: "hello world", Data: logrus.Fields{}})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+		if v := jsoniter.Get(data, "message").ToString(); v != "hello" {
+			t.Fatalf(`Format() output "message", Expected=%q, Actual=%q`, "hello", v)
+		}
+		if v := jsoniter.Get(data, "truncated").ToBool(); !v {
+			t.Fatalf(`Format() output "truncated", Expected=true, Actual=%v`, v)
+		}
+		if v := jsoniter.Get(data, "truncations", 0, "path").ToString(); v != "msg" {
+			t.Fatalf(`Format() output "truncations.0.path", Expected=%q, Actual=%q`, "msg", v)
+		}
+	})
+
+	t.Run("OTLP", func(t *testing.T) {
+		f, _ := NewFormatter(APPLogsV1, WithEncoding(EncodingOTLP), WithLimits(Limits{MaxMessageLength: 5}))
+		data, err := f.Format(&logrus.Entry{Level: logrus.InfoLevel, Time: now, Message: "hello world", Data: logrus.Fields{}})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+		record := jsoniter.Get(data, "resourceLogs", 0, "scopeLogs", 0, "logRecords", 0)
+		if v := record.Get("body", "stringValue").ToString(); v != "hello" {
+			t.Fatalf(`Format() output "body", Expected=%q, Actual=%q`, "hello", v)
+		}
+		if v := otlpAttribute(record.Get("attributes"), "truncated").Get("boolValue").ToBool(); !v {
+			t.Fatalf(`Format() output "truncated", Expected=true, Actual=%v`, v)
+		}
+		item := otlpAttribute(record.Get("attributes"), "truncations").Get("arrayValue", "values", 0, "kvlistValue", "values")
+		if v := otlpAttribute(item, "path").Get("stringValue").ToString(); v != "msg" {
+			t.Fatalf(`Format() output "truncations.0.path", Expected=%q, Actual=%q`, "msg", v)
+		}
+		if v := otlpAttribute(item, "length").Get("intValue").ToString(); v != "11" {
+			t.Fatalf(`Format() output "truncations.0.length", Expected=%q, Actual=%q`, "11", v)
+		}
+	})
+
+	t.Run("HTTPRequestV1", func(t *testing.T) {
+		f, _ := NewFormatter(HTTPRequestV1, WithEncoding(EncodingLogfmt), WithLimits(Limits{MaxStringLength: 2}))
+		headers := http.Header{}
+		headers.Set("x-test", "abc")
+		data, err := f.Format(&logrus.Entry{
+			Level: logrus.InfoLevel,
+			Time:  now,
+			Data: logrus.Fields{
+				HTTPRequestReqKey: &http.Request{
+					RemoteAddr: "1.2.3.4:1234",
+					Header:     headers,
+					Method:     http.MethodGet,
+					URL:        &url.URL{Path: "/api"},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+		expected := `schema=http.request.v1 level=info time=2019-08-12T10:13:48Z ip=1.2.3.4 method=GET path=/api headers.X-Test=ab ` +
+			`truncated=true truncations.0.path=headers.X-Test truncations.0.reason=length truncations.0.length=3` + "\n"
+		if string(data) != expected {
+			t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, data)
+		}
+	})
+}
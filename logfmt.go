@@ -0,0 +1,139 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+// encodeLogfmt 将字段编码为一行logfmt
+// 嵌套的map按key排序后以"."连接展开，数组以下标展开，保证相同内容的输出顺序稳定
+func encodeLogfmt(fs []field) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	for _, f := range fs {
+		if err := writeLogfmt(buf, f.Key, f.Value); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+func writeLogfmt(buf *bytes.Buffer, key string, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		writeLogfmtPair(buf, key, "null")
+	case string:
+		writeLogfmtPair(buf, key, logfmtString(v))
+	case bool:
+		writeLogfmtPair(buf, key, strconv.FormatBool(v))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		writeLogfmtPair(buf, key, fmt.Sprint(v))
+	case float32:
+		writeLogfmtPair(buf, key, strconv.FormatFloat(float64(v), 'g', -1, 32))
+	case float64:
+		writeLogfmtPair(buf, key, strconv.FormatFloat(v, 'g', -1, 64))
+	case jsoniter.Number:
+		writeLogfmtPair(buf, key, string(v))
+	case error:
+		writeLogfmtPair(buf, key, logfmtString(v.Error()))
+	case fmt.Stringer:
+		writeLogfmtPair(buf, key, logfmtString(v.String()))
+	case []field:
+		for _, f := range v {
+			if err := writeLogfmt(buf, key+"."+f.Key, f.Value); err != nil {
+				return err
+			}
+		}
+	case logrus.Fields:
+		return writeLogfmtMap(buf, key, v)
+	case map[string]interface{}:
+		return writeLogfmtMap(buf, key, v)
+	case map[string]string:
+		m := make(map[string]interface{}, len(v))
+		for k, s := range v {
+			m[k] = s
+		}
+		return writeLogfmtMap(buf, key, m)
+	case []string:
+		for i, s := range v {
+			writeLogfmtPair(buf, key+"."+strconv.Itoa(i), logfmtString(s))
+		}
+	case []interface{}:
+		for i, e := range v {
+			if err := writeLogfmt(buf, key+"."+strconv.Itoa(i), e); err != nil {
+				return err
+			}
+		}
+	default:
+		// 其他类型按json规则转换为基础类型后再展开
+		plain, err := toPlain(v)
+		if err != nil {
+			return err
+		}
+		return writeLogfmt(buf, key, plain)
+	}
+
+	return nil
+}
+
+func writeLogfmtMap(buf *bytes.Buffer, key string, m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := writeLogfmt(buf, key+"."+k, m[k]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeLogfmtPair(buf *bytes.Buffer, key, value string) {
+	if buf.Len() > 0 {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(logfmtKey(key))
+	buf.WriteByte('=')
+	buf.WriteString(value)
+}
+
+// logfmtKey 将key中logfmt不允许出现的字符替换为"_"
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// logfmtString 值为空或包含空白、引号、等号、反斜杠及不可打印字符时加上引号并转义
+func logfmtString(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r == '"' || r == '=' || r == '\\' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+
+	return s
+}
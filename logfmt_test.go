@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLogfmtEncoding(t *testing.T) {
+	now := time.Date(2019, 8, 12, 10, 13, 48, 0, time.UTC)
+
+	t.Run("APPLogsV1", func(t *testing.T) {
+		f := &APPLogsV1Formatter{TimeLayout: time.RFC3339, Service: "svc", Encoding: EncodingLogfmt}
+		entry := &logrus.Entry{
+			Level:   logrus.InfoLevel,
+			Time:    now,
+			Message: `say "hi"`,
+			Data: logrus.Fields{
+				ChannelKey:      "payment",
+				"user":          map[string]interface{}{"id": 1, "name": "a b"},
+				"tags":          []string{"x", "y=z"},
+				"empty":         "",
+				"a key":         true,
+				logrus.ErrorKey: errors.New("e"),
+			},
+		}
+
+		expected := `schema=app.logs.v1 service=svc channel=payment level=info time=2019-08-12T10:13:48Z msg="say \"hi\"" ` +
+			`ctx.a_key=true ctx.empty="" ctx.error.msg=e ctx.tags.0=x ctx.tags.1="y=z" ctx.user.id=1 ctx.user.name="a b"` + "\n"
+
+		// 多次Format校验输出顺序稳定
+		for i := 0; i < 10; i++ {
+			data, err := f.Format(entry)
+			if err != nil {
+				t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+			}
+			if string(data) != expected {
+				t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, data)
+			}
+		}
+	})
+
+	t.Run("HTTPRequestV1", func(t *testing.T) {
+		f := &HTTPRequestV1Formatter{TimeLayout: time.RFC3339, Encoding: EncodingLogfmt}
+		headers := http.Header{}
+		headers.Set("x-test", "1")
+		headers.Add("accept", "a")
+		headers.Add("accept", "b")
+
+		entry := &logrus.Entry{
+			Level: logrus.InfoLevel,
+			Time:  now,
+			Data: logrus.Fields{
+				HTTPRequestReqKey: &http.Request{
+					RemoteAddr: "1.2.3.4:1234",
+					Header:     headers,
+					Method:     http.MethodGet,
+					URL:        &url.URL{Path: "/api", RawQuery: "foo=bar"},
+				},
+				"status": 200,
+			},
+		}
+
+		expected := `schema=http.request.v1 level=info time=2019-08-12T10:13:48Z ip=1.2.3.4 method=GET path=/api ` +
+			`headers.Accept="a, b" headers.X-Test=1 get.foo=bar extra.status=200` + "\n"
+		data, err := f.Format(entry)
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+		if string(data) != expected {
+			t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, data)
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		f := &APPLogsV1Formatter{Encoding: "xml"}
+		if _, err := f.Format(&logrus.Entry{Data: logrus.Fields{}}); err == nil {
+			t.Fatalf("Format() error, Expected=%q, Actual=nil", ErrEncodingNotFound)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package logger
 import (
 	"fmt"
 
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
@@ -19,16 +20,48 @@ const (
 var (
 	// ErrFormatterNotFound 找不到对应规范的日志格式化对象
 	ErrFormatterNotFound = fmt.Errorf("log formatter not found")
+	// ErrOptionNotSupported 日志规范或编码方式不支持的配置项
+	ErrOptionNotSupported = fmt.Errorf("log option not supported")
 )
 
 // NewLogger 创建新的日志对象
-func NewLogger(s Standard) (*logrus.Logger, error) {
-	f, err := NewFormatter(s)
+func NewLogger(s Standard, opts ...Option) (*logrus.Logger, error) {
+	o := newOptions(opts)
+	f, err := newFormatter(s, o)
 	if err != nil {
 		return nil, err
 	}
 
+	if o.quotas != nil {
+		f = NewQuotaFormatter(f, *o.quotas)
+	}
+	if o.sampling != nil {
+		// 按msg采样与汇总只适用于运行日志，请求日志使用WithRequestSampling
+		if s != APPLogsV1 {
+			return nil, errors.Wrapf(ErrOptionNotSupported, "sampling for log standard %q", s)
+		}
+		f = NewSamplingFormatter(f, *o.sampling)
+	}
+	if o.dedupe != nil {
+		f = NewDedupeFormatter(f, *o.dedupe)
+	}
+	if o.requestSampling != nil {
+		f = NewRequestSamplingFormatter(f, *o.requestSampling)
+	}
+	if o.slowRequests != nil {
+		f = NewSlowRequestFormatter(f, *o.slowRequests)
+	}
+
 	l := logrus.New()
 	l.SetFormatter(f)
+	if o.output != nil {
+		l.SetOutput(o.output)
+	}
+	if o.level != nil {
+		l.SetLevel(*o.level)
+	}
+	for _, h := range o.hooks {
+		l.AddHook(h)
+	}
 	return l, nil
 }
@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type countHook struct {
+	fired int
+}
+
+func (h *countHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *countHook) Fire(*logrus.Entry) error {
+	h.fired++
+	return nil
+}
+
+func TestNewLogger(t *testing.T) {
+	if _, err := NewLogger("undefined"); err == nil {
+		t.Fatalf("Test NewLogger(), Expected=%q, Actual=nil", ErrFormatterNotFound)
+	}
+	if _, err := NewLogger(APPLogsV1, WithEncoding("xml")); errors.Cause(err) != ErrEncodingNotFound {
+		t.Fatalf("Test NewLogger() encoding, Expected=%q, Actual=%v", ErrEncodingNotFound, err)
+	}
+	if _, err := NewFormatter(HTTPRequestV1, WithEncoding("xml")); errors.Cause(err) != ErrEncodingNotFound {
+		t.Fatalf("Test NewFormatter() encoding, Expected=%q, Actual=%v", ErrEncodingNotFound, err)
+	}
+
+	buf := &bytes.Buffer{}
+	hook := &countHook{}
+	l, err := NewLogger(APPLogsV1,
+		WithService("svc"),
+		WithOutput(buf),
+		WithLevel(logrus.DebugLevel),
+		WithHooks(hook),
+	)
+	if err != nil {
+		t.Fatalf("Test NewLogger(), Expected=nil, Actual=%q", err.Error())
+	}
+
+	if l.GetLevel() != logrus.DebugLevel {
+		t.Fatalf("Test NewLogger() level, Expected=%q, Actual=%q", logrus.DebugLevel, l.GetLevel())
+	}
+
+	l.Debug("hello")
+	if hook.fired != 1 {
+		t.Fatalf("Test NewLogger() hooks, Expected=1, Actual=%d", hook.fired)
+	}
+
+	if v := jsoniter.Get(buf.Bytes(), "service").ToString(); v != "svc" {
+		t.Fatalf("Test NewLogger() output, Expected=%q, Actual=%q", "svc", v)
+	}
+}
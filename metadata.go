@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"os"
+)
+
+// Kubernetes downward API 注入的环境变量名
+const (
+	EnvPodName      = "POD_NAME"
+	EnvPodNamespace = "POD_NAMESPACE"
+	EnvNodeName     = "NODE_NAME"
+)
+
+// Metadata 服务实例元信息，用于区分同一服务的不同副本
+type Metadata struct {
+	Host      string `json:"host,omitempty"`
+	PID       int    `json:"pid,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Node      string `json:"node,omitempty"`
+}
+
+// NewMetadata 从主机名、进程号及Kubernetes downward API环境变量收集元信息
+// 应在程序启动时调用一次，version 通常在编译时通过 -ldflags 注入
+func NewMetadata(version string) *Metadata {
+	host, _ := os.Hostname()
+
+	return &Metadata{
+		Host:      host,
+		PID:       os.Getpid(),
+		Version:   version,
+		Pod:       os.Getenv(EnvPodName),
+		Namespace: os.Getenv(EnvPodNamespace),
+		Node:      os.Getenv(EnvNodeName),
+	}
+}
@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewMetadata(t *testing.T) {
+	os.Setenv(EnvPodName, "pod-1")
+	os.Setenv(EnvPodNamespace, "default")
+	os.Setenv(EnvNodeName, "node-1")
+	defer func() {
+		os.Unsetenv(EnvPodName)
+		os.Unsetenv(EnvPodNamespace)
+		os.Unsetenv(EnvNodeName)
+	}()
+
+	m := NewMetadata("v1.0.0")
+	if m.PID != os.Getpid() {
+		t.Fatalf("Test NewMetadata() pid, Expected=%d, Actual=%d", os.Getpid(), m.PID)
+	}
+	if m.Version != "v1.0.0" || m.Pod != "pod-1" || m.Namespace != "default" || m.Node != "node-1" {
+		t.Fatalf("Test NewMetadata(), Actual=%+v", m)
+	}
+
+	f, _ := NewFormatter(APPLogsV1, WithMetadata(m))
+	data, err := f.Format(&logrus.Entry{Time: time.Now(), Data: logrus.Fields{}})
+	if err != nil {
+		t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+	}
+
+	cases := []struct {
+		path     []interface{}
+		expected string
+	}{
+		{path: []interface{}{"meta", "version"}, expected: "v1.0.0"},
+		{path: []interface{}{"meta", "pod"}, expected: "pod-1"},
+		{path: []interface{}{"meta", "namespace"}, expected: "default"},
+		{path: []interface{}{"meta", "node"}, expected: "node-1"},
+	}
+	for _, c := range cases {
+		if v := jsoniter.Get(data, c.path...).ToString(); v != c.expected {
+			t.Fatalf(`Format() output %q, Expected=%q, Actual=%q`, c.path, c.expected, v)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package logger
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Option NewLogger与NewFormatter的可选配置项
+type Option func(*options)
+
+type options struct {
+	timeLayout   string
+	timeLocation *time.Location
+	// 是否设置了Unix时间戳
+	epoch       bool
+	epochUnit   time.Duration
+	epochKey    string
+	service     string
+	environment string
+	metadata    *Metadata
+	encoding    Encoding
+
+	ecsNamespace        string
+	cloudLoggingProject string
+	keyMap              map[string]string
+	sortKeys            bool
+	flatten             bool
+	flattenDepth        int
+	limits              Limits
+	typeStable          TypeStable
+	valueEncoders       map[reflect.Type]ValueEncoder
+	defaultChannel      string
+	requireChannel      bool
+
+	output          io.Writer
+	level           *logrus.Level
+	hooks           []logrus.Hook
+	sampling        *Sampling
+	dedupe          *Dedupe
+	quotas          *Quotas
+	requestSampling *RequestSampling
+	slowRequests    *SlowRequests
+
+	otlpClient        *http.Client
+	otlpHeaders       map[string]string
+	otlpBatchSize     int
+	otlpFlushInterval time.Duration
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		timeLayout: time.RFC3339,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithTimeLayout 设置日志时间格式，默认time.RFC3339
+func WithTimeLayout(layout string) Option {
+	return func(o *options) {
+		o.timeLayout = layout
+	}
+}
+
+// WithTimeLocation 设置输出时间使用的时区，如time.UTC，默认使用日志时间自身的时区
+func WithTimeLocation(loc *time.Location) Option {
+	return func(o *options) {
+		o.timeLocation = loc
+	}
+}
+
+// WithEpoch time字段以json数字输出指定精度的Unix时间戳，unit只能为time.Second、time.Millisecond、time.Microsecond或time.Nanosecond
+func WithEpoch(unit time.Duration) Option {
+	return func(o *options) {
+		o.epoch = true
+		o.epochUnit = unit
+		o.epochKey = ""
+	}
+}
+
+// WithEpochField 在time字段之后以key额外输出指定精度的Unix时间戳，便于排序与索引，unit的取值与WithEpoch相同
+func WithEpochField(key string, unit time.Duration) Option {
+	return func(o *options) {
+		o.epoch = true
+		o.epochUnit = unit
+		o.epochKey = key
+	}
+}
+
+// WithService 设置服务名称
+func WithService(service string) Option {
+	return func(o *options) {
+		o.service = service
+	}
+}
+
+// WithEnvironment 设置部署环境
+func WithEnvironment(env string) Option {
+	return func(o *options) {
+		o.environment = env
+	}
+}
+
+// WithMetadata 设置服务实例元信息，见NewMetadata
+func WithMetadata(m *Metadata) Option {
+	return func(o *options) {
+		o.metadata = m
+	}
+}
+
+// WithEncoding 设置日志编码方式，默认EncodingJSON
+func WithEncoding(enc Encoding) Option {
+	return func(o *options) {
+		o.encoding = enc
+	}
+}
+
+// WithECSNamespace 设置EncodingECS编码时自定义字段所在的命名空间，默认"custom"
+func WithECSNamespace(ns string) Option {
+	return func(o *options) {
+		o.ecsNamespace = ns
+	}
+}
+
+// WithCloudLoggingProject 设置EncodingCloudLogging编码时trace所属的GCP项目ID
+func WithCloudLoggingProject(project string) Option {
+	return func(o *options) {
+		o.cloudLoggingProject = project
+	}
+}
+
+// WithKeyMap 设置顶层字段名映射，如{"msg": "message", "time": "@timestamp"}，
+// 包括ctx、extra等容器字段，映射不合法时NewFormatter与NewLogger返回ErrInvalidKeyMap
+func WithKeyMap(m map[string]string) Option {
+	return func(o *options) {
+		o.keyMap = m
+	}
+}
+
+// WithSortedKeys 递归地按key排序输出ctx、extra、headers等嵌套的map，使相同内容的输出稳定
+func WithSortedKeys() Option {
+	return func(o *options) {
+		o.sortKeys = true
+	}
+}
+
+// WithFlatten 将ctx(app.logs.v1)或extra、get、post(http.request.v1)展开为"."连接的顶层字段，
+// depth为容器之下最多展开的层数，小于等于0时不限制，数组不展开
+func WithFlatten(depth int) Option {
+	return func(o *options) {
+		o.flatten = true
+		o.flattenDepth = depth
+	}
+}
+
+// WithLimits 设置日志内容的大小限制，超出限制的内容会被截断并记录在"truncations"字段中
+func WithLimits(l Limits) Option {
+	return func(o *options) {
+		o.limits = l
+	}
+}
+
+// WithTypeStable 使ctx等容器中同名字段的类型保持稳定，按json类型为key添加后缀或将指定字段转换为字符串，
+// 可通过TypeTracker记录出现过多种类型的字段
+func WithTypeStable(ts TypeStable) Option {
+	return func(o *options) {
+		o.typeStable = ts
+	}
+}
+
+// WithValueEncoder 为ctx、extra中与sample类型相同的值注册转换方式，优先于内置的转换方式，
+// 如WithValueEncoder(Money{}, func(v interface{}) interface{} { return v.(Money).String() })
+func WithValueEncoder(sample interface{}, enc ValueEncoder) Option {
+	return func(o *options) {
+		if o.valueEncoders == nil {
+			o.valueEncoders = map[reflect.Type]ValueEncoder{}
+		}
+		o.valueEncoders[reflect.TypeOf(sample)] = enc
+	}
+}
+
+// WithDefaultChannel 设置app.logs.v1日志条目缺少channel时使用的channel，如"default"
+func WithDefaultChannel(name string) Option {
+	return func(o *options) {
+		o.defaultChannel = name
+	}
+}
+
+// WithChannelRequired 拒绝缺少channel的app.logs.v1日志条目，Format返回ErrChannelRequired，
+// 设置了WithDefaultChannel时不生效
+func WithChannelRequired() Option {
+	return func(o *options) {
+		o.requireChannel = true
+	}
+}
+
+// WithOutput 设置日志输出，默认os.Stderr，仅对NewLogger生效
+func WithOutput(w io.Writer) Option {
+	return func(o *options) {
+		o.output = w
+	}
+}
+
+// WithLevel 设置日志级别，默认logrus.InfoLevel，仅对NewLogger生效
+func WithLevel(level logrus.Level) Option {
+	return func(o *options) {
+		o.level = &level
+	}
+}
+
+// WithHooks 添加日志钩子，仅对NewLogger生效
+func WithHooks(hooks ...logrus.Hook) Option {
+	return func(o *options) {
+		o.hooks = append(o.hooks, hooks...)
+	}
+}
+
+// WithSampling 对日志采样，防止大量重复的日志挤占日志管道，仅对NewLogger生效，只支持app.logs.v1日志
+func WithSampling(s Sampling) Option {
+	return func(o *options) {
+		o.sampling = &s
+	}
+}
+
+// WithDedupe 抑制窗口内重复的日志，窗口结束后输出一条汇总日志，仅对NewLogger生效
+func WithDedupe(d Dedupe) Option {
+	return func(o *options) {
+		o.dedupe = &d
+	}
+}
+
+// WithQuotas 按channel限制日志输出量，仅对NewLogger生效
+func WithQuotas(q Quotas) Option {
+	return func(o *options) {
+		o.quotas = &q
+	}
+}
+
+// WithRequestSampling 按请求方法、路径与状态码类别对http.request.v1日志采样，仅对NewLogger生效
+func WithRequestSampling(s RequestSampling) Option {
+	return func(o *options) {
+		o.requestSampling = &s
+	}
+}
+
+// WithSlowRequests 检测耗时超出阈值的请求，提升http.request.v1日志的级别并标记，仅对NewLogger生效
+func WithSlowRequests(s SlowRequests) Option {
+	return func(o *options) {
+		o.slowRequests = &s
+	}
+}
+
+// WithOTLPClient 设置OTLPExporter发送日志使用的http.Client，默认超时10秒，仅对NewOTLPExporter生效
+func WithOTLPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.otlpClient = c
+	}
+}
+
+// WithOTLPHeaders 设置OTLPExporter发送日志时附加的请求头，仅对NewOTLPExporter生效
+func WithOTLPHeaders(headers map[string]string) Option {
+	return func(o *options) {
+		o.otlpHeaders = headers
+	}
+}
+
+// WithOTLPBatchSize 设置OTLPExporter每次发送的最大日志条数，默认DefaultOTLPBatchSize，仅对NewOTLPExporter生效
+func WithOTLPBatchSize(n int) Option {
+	return func(o *options) {
+		o.otlpBatchSize = n
+	}
+}
+
+// WithOTLPFlushInterval 设置OTLPExporter定时发送的间隔，默认DefaultOTLPFlushInterval，仅对NewOTLPExporter生效
+func WithOTLPFlushInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.otlpFlushInterval = d
+	}
+}
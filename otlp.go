@@ -0,0 +1,291 @@
+package logger
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// otlpScopeName OTLP日志记录的instrumentation scope名称
+	otlpScopeName = "github.com/cowsvagina/go-logger"
+)
+
+// otlpRequest 将日志内容映射为只包含一条LogRecord的OTLP/JSON ExportLogsServiceRequest
+// 每行一个请求的格式可直接被OpenTelemetry Collector的otlpjsonfile receiver读取
+func otlpRequest(entry *logrus.Entry, data logData) []field {
+	return otlpExportRequest(otlpResource(data), []interface{}{otlpLogRecord(entry, data)})
+}
+
+func otlpExportRequest(resource []interface{}, records []interface{}) []field {
+	return []field{
+		{"resourceLogs", []interface{}{
+			[]field{
+				{"resource", []field{{"attributes", resource}}},
+				{"scopeLogs", []interface{}{
+					[]field{
+						{"scope", []field{{"name", otlpScopeName}}},
+						{"logRecords", records},
+					},
+				}},
+			},
+		}},
+	}
+}
+
+func otlpResource(data logData) []interface{} {
+	switch d := data.(type) {
+	case *APPLogsV1Data:
+		return otlpResourceAttributes(d.Service, d.Environment, d.Metadata)
+	case *HTTPRequestV1Data:
+		return otlpResourceAttributes(d.Service, d.Environment, d.Metadata)
+	}
+
+	return []interface{}{}
+}
+
+// otlpResourceAttributes 由服务名称、部署环境及服务实例元信息生成resource attributes
+// 返回的[]interface{}中每个元素为一个KeyValue
+func otlpResourceAttributes(service, env string, meta *Metadata) []interface{} {
+	attrs := make([]interface{}, 0, 8)
+	add := func(key, value string) {
+		if value != "" {
+			attrs = append(attrs, otlpKeyValue(key, value))
+		}
+	}
+	add("service.name", service)
+	add("deployment.environment", env)
+	if meta != nil {
+		add("service.version", meta.Version)
+		add("host.name", meta.Host)
+		if meta.PID != 0 {
+			attrs = append(attrs, otlpKeyValue("process.pid", meta.PID))
+		}
+		add("k8s.pod.name", meta.Pod)
+		add("k8s.namespace.name", meta.Namespace)
+		add("k8s.node.name", meta.Node)
+	}
+
+	return attrs
+}
+
+// otlpLogRecord 将日志内容映射为OTLP LogRecord
+// app.logs.v1的msg映射为body，channel与ctx映射为attributes，_encode_errors与截断记录同样映射为attributes；
+// http.request.v1的请求信息按OpenTelemetry语义约定映射为attributes，body为"METHOD path"
+func otlpLogRecord(entry *logrus.Entry, data logData) []field {
+	severityNumber, severityText := otlpSeverity(entry.Level)
+	record := []field{
+		{"timeUnixNano", strconv.FormatInt(entry.Time.UnixNano(), 10)},
+		{"severityNumber", severityNumber},
+		{"severityText", severityText},
+	}
+
+	attrs := make([]interface{}, 0, 8)
+	var traceID, spanID string
+	switch d := data.(type) {
+	case *APPLogsV1Data:
+		record = append(record, field{"body", otlpAnyValue(d.Message)})
+		attrs = append(attrs, otlpKeyValue("schema", d.Schema))
+		if d.Channel != "" {
+			attrs = append(attrs, otlpKeyValue("channel", d.Channel))
+		}
+		attrs = append(attrs, otlpAttributes(d.Context)...)
+		attrs = otlpFieldAttributes(attrs, truncationFields(encodeErrorFields(nil, d.encodeErrors), d.truncations, d.collapsed))
+		traceID, spanID = stringValue(d.Context[TraceIDKey]), stringValue(d.Context[SpanIDKey])
+	case *HTTPRequestV1Data:
+		record = append(record, field{"body", otlpAnyValue(d.Method + " " + d.Path)})
+		attrs = append(attrs,
+			otlpKeyValue("schema", d.Schema),
+			otlpKeyValue("http.request.method", d.Method),
+			otlpKeyValue("url.path", d.Path),
+			otlpKeyValue("client.address", d.IP),
+		)
+		if d.User != "" {
+			attrs = append(attrs, otlpKeyValue("user.id", d.User))
+		}
+		if status, ok := intValue(d.Extra[HTTPRequestStatusKey]); ok {
+			attrs = append(attrs, otlpKeyValue("http.response.status_code", status))
+		}
+		headers := make([]string, 0, len(d.Headers))
+		for k := range d.Headers {
+			headers = append(headers, k)
+		}
+		sort.Strings(headers)
+		for _, k := range headers {
+			attrs = append(attrs, otlpKeyValue("http.request.header."+strings.ToLower(k), d.Headers[k]))
+		}
+		if len(d.Get) > 0 {
+			attrs = append(attrs, otlpKeyValue("get", d.Get))
+		}
+		if len(d.Post) > 0 {
+			attrs = append(attrs, otlpKeyValue("post", d.Post))
+		}
+		attrs = append(attrs, otlpAttributes(d.Extra)...)
+		attrs = otlpFieldAttributes(attrs, truncationFields(encodeErrorFields(nil, d.encodeErrors), d.truncations, d.collapsed))
+		traceID, spanID = stringValue(d.Extra[TraceIDKey]), stringValue(d.Extra[SpanIDKey])
+		if req, ok := entry.Data[HTTPRequestReqKey].(*http.Request); ok && traceID == "" {
+			traceID, spanID = parseTraceHeader(req.Header)
+		}
+	}
+
+	record = append(record, field{"attributes", attrs})
+	if traceID != "" {
+		record = append(record, field{"traceId", traceID})
+	}
+	if spanID != "" {
+		record = append(record, field{"spanId", spanID})
+	}
+
+	return record
+}
+
+// otlpSeverity logrus日志级别对应的OpenTelemetry SeverityNumber与SeverityText
+func otlpSeverity(level logrus.Level) (int, string) {
+	switch level {
+	case logrus.TraceLevel:
+		return 1, "TRACE"
+	case logrus.DebugLevel:
+		return 5, "DEBUG"
+	case logrus.InfoLevel:
+		return 9, "INFO"
+	case logrus.WarnLevel:
+		return 13, "WARN"
+	case logrus.ErrorLevel:
+		return 17, "ERROR"
+	case logrus.FatalLevel:
+		return 21, "FATAL"
+	case logrus.PanicLevel:
+		return 24, "FATAL4"
+	}
+
+	return 0, ""
+}
+
+// otlpAttributes 按key排序将字段映射为attributes
+// logrus.ErrorKey对应的错误信息按语义约定映射为exception.message与exception.stacktrace
+func otlpAttributes(fields logrus.Fields) []interface{} {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		if k == TraceIDKey || k == SpanIDKey {
+			continue
+		}
+		if msg, trace, ok := errInfo(fields[k]); ok && k == logrus.ErrorKey {
+			attrs = append(attrs, otlpKeyValue("exception.message", msg))
+			if len(trace) > 0 {
+				attrs = append(attrs, otlpKeyValue("exception.stacktrace", strings.Join(trace, "\n")))
+			}
+			continue
+		}
+		attrs = append(attrs, otlpKeyValue(k, fields[k]))
+	}
+
+	return attrs
+}
+
+// otlpFieldAttributes 将_encode_errors、截断记录等附加字段依次映射为attributes
+func otlpFieldAttributes(attrs []interface{}, fs []field) []interface{} {
+	for _, f := range fs {
+		attrs = append(attrs, otlpKeyValue(f.Key, f.Value))
+	}
+
+	return attrs
+}
+
+func otlpKeyValue(key string, value interface{}) []field {
+	return []field{{"key", key}, {"value", otlpAnyValue(value)}}
+}
+
+// otlpAnyValue 将值映射为OTLP/JSON AnyValue，int64按protobuf JSON规范编码为字符串
+func otlpAnyValue(value interface{}) []field {
+	switch v := value.(type) {
+	case nil:
+		return []field{}
+	case string:
+		return []field{{"stringValue", v}}
+	case bool:
+		return []field{{"boolValue", v}}
+	case float32:
+		return otlpDoubleValue(float64(v), "float32")
+	case float64:
+		return otlpDoubleValue(v, "float64")
+	case jsoniter.Number:
+		if i, err := v.Int64(); err == nil {
+			return []field{{"intValue", strconv.FormatInt(i, 10)}}
+		}
+		f, _ := v.Float64()
+		return []field{{"doubleValue", f}}
+	case []byte:
+		return []field{{"bytesValue", v}}
+	case error:
+		return []field{{"stringValue", v.Error()}}
+	case fmt.Stringer:
+		return []field{{"stringValue", v.String()}}
+	case []field:
+		values := make([]interface{}, len(v))
+		for i, f := range v {
+			values[i] = otlpKeyValue(f.Key, f.Value)
+		}
+		return []field{{"kvlistValue", []field{{"values", values}}}}
+	case logrus.Fields:
+		return otlpKvlist(v)
+	case map[string]interface{}:
+		return otlpKvlist(v)
+	case []string:
+		values := make([]interface{}, len(v))
+		for i, s := range v {
+			values[i] = otlpAnyValue(s)
+		}
+		return []field{{"arrayValue", []field{{"values", values}}}}
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, e := range v {
+			values[i] = otlpAnyValue(e)
+		}
+		return []field{{"arrayValue", []field{{"values", values}}}}
+	}
+
+	if i, ok := intValue(value); ok {
+		return []field{{"intValue", strconv.FormatInt(i, 10)}}
+	}
+
+	plain, err := toPlain(value)
+	if err != nil {
+		return []field{{"stringValue", fmt.Sprintf("%+v", value)}}
+	}
+	return otlpAnyValue(plain)
+}
+
+// otlpDoubleValue NaN与±Inf无法以json编码，映射为占位符字符串
+func otlpDoubleValue(v float64, typ string) []field {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return []field{{"stringValue", encodePlaceholder(typ, fmt.Sprintf("unsupported value: %v", v))}}
+	}
+	return []field{{"doubleValue", v}}
+}
+
+func otlpKvlist(m map[string]interface{}) []field {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	values := make([]interface{}, len(keys))
+	for i, k := range keys {
+		values[i] = otlpKeyValue(k, m[k])
+	}
+
+	return []field{{"kvlistValue", []field{{"values", values}}}}
+}
@@ -0,0 +1,204 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultOTLPBatchSize OTLPExporter每次发送的最大日志条数
+	DefaultOTLPBatchSize = 512
+	// DefaultOTLPFlushInterval OTLPExporter定时发送的间隔
+	DefaultOTLPFlushInterval = 5 * time.Second
+)
+
+var (
+	_ logrus.Hook = (*OTLPExporter)(nil)
+)
+
+// OTLPExporter 以OTLP/HTTP JSON协议将日志批量发送至OpenTelemetry Collector
+// 实现了logrus.Hook，可通过WithHooks添加到日志对象，程序退出前应调用Close发送剩余日志
+type OTLPExporter struct {
+	endpoint      string
+	client        *http.Client
+	headers       map[string]string
+	batchSize     int
+	flushInterval time.Duration
+	extractor     extractor
+	resource      []interface{}
+
+	mu      sync.Mutex
+	records []interface{}
+	// 后台发送失败的错误，在下一次Fire时返回给logrus
+	err error
+
+	flush     chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewOTLPExporter 创建发送指定规范日志的OTLPExporter，endpoint如"http://localhost:4318/v1/logs"
+// 日志内容相关的配置项(WithService、WithEnvironment、WithMetadata等)与NewFormatter一致
+func NewOTLPExporter(endpoint string, s Standard, opts ...Option) (*OTLPExporter, error) {
+	o := newOptions(opts)
+	f, err := newFormatter(s, o)
+	if err != nil {
+		return nil, err
+	}
+	ext, ok := f.(extractor)
+	if !ok {
+		return nil, errors.Wrapf(ErrFormatterNotFound, "log standard %q", s)
+	}
+
+	e := &OTLPExporter{
+		endpoint:      endpoint,
+		client:        o.otlpClient,
+		headers:       o.otlpHeaders,
+		batchSize:     o.otlpBatchSize,
+		flushInterval: o.otlpFlushInterval,
+		extractor:     ext,
+		resource:      otlpResourceAttributes(o.service, o.environment, o.metadata),
+		flush:         make(chan struct{}, 1),
+		done:          make(chan struct{}),
+	}
+	if e.client == nil {
+		e.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if e.batchSize <= 0 {
+		e.batchSize = DefaultOTLPBatchSize
+	}
+	if e.flushInterval <= 0 {
+		e.flushInterval = DefaultOTLPFlushInterval
+	}
+
+	e.wg.Add(1)
+	go e.loop()
+
+	return e, nil
+}
+
+// Levels implements logrus.Hook interface
+func (e *OTLPExporter) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook interface
+func (e *OTLPExporter) Fire(entry *logrus.Entry) error {
+	data, err := e.extractor.extract(entry)
+	if err != nil {
+		return err
+	}
+	// 每条日志单独编码，无法编码的日志只丢弃其本身，不影响同一批次的其他日志
+	record, err := encodeJSONFields(jsoniter.ConfigDefault, otlpLogRecord(entry, data))
+	data.release()
+	if err != nil {
+		return errors.Wrap(err, "otlp encode log")
+	}
+
+	e.mu.Lock()
+	e.records = append(e.records, jsoniter.RawMessage(bytes.TrimSuffix(record, []byte("\n"))))
+	full := len(e.records) >= e.batchSize
+	err, e.err = e.err, nil
+	e.mu.Unlock()
+
+	if full {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+
+	return err
+}
+
+// Flush 立即发送所有缓存的日志，某一批发送失败时继续发送其余批次，返回第一个错误
+func (e *OTLPExporter) Flush() error {
+	e.mu.Lock()
+	records := e.records
+	e.records = nil
+	e.mu.Unlock()
+
+	var first error
+	for len(records) > 0 {
+		n := len(records)
+		if n > e.batchSize {
+			n = e.batchSize
+		}
+		if err := e.send(records[:n]); err != nil && first == nil {
+			first = err
+		}
+		records = records[n:]
+	}
+
+	return first
+}
+
+// Close 停止定时发送并发送剩余日志
+func (e *OTLPExporter) Close() error {
+	e.closeOnce.Do(func() {
+		close(e.done)
+	})
+	e.wg.Wait()
+
+	return e.Flush()
+}
+
+func (e *OTLPExporter) loop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+		case <-e.flush:
+		}
+
+		if err := e.Flush(); err != nil {
+			e.mu.Lock()
+			e.err = err
+			e.mu.Unlock()
+		}
+	}
+}
+
+func (e *OTLPExporter) send(records []interface{}) error {
+	body, err := encodeJSONFields(jsoniter.ConfigDefault, otlpExportRequest(e.resource, records))
+	if err != nil {
+		return errors.Wrap(err, "otlp encode logs")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "otlp export")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "otlp export")
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("otlp export: unexpected response status %q", resp.Status)
+	}
+
+	return nil
+}
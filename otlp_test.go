@@ -0,0 +1,249 @@
+package logger
+
+import (
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// otlpAttribute 从OTLP/JSON attributes中查找key对应的值
+func otlpAttribute(attrs jsoniter.Any, key string) jsoniter.Any {
+	for i := 0; i < attrs.Size(); i++ {
+		if attrs.Get(i, "key").ToString() == key {
+			return attrs.Get(i, "value")
+		}
+	}
+
+	return nil
+}
+
+func TestOTLPEncoding(t *testing.T) {
+	now := time.Unix(1565576028, 5)
+
+	t.Run("APPLogsV1", func(t *testing.T) {
+		f := &APPLogsV1Formatter{
+			TimeLayout:  time.RFC3339,
+			Service:     "svc",
+			Environment: "prod",
+			Encoding:    EncodingOTLP,
+		}
+		data, err := f.Format(&logrus.Entry{
+			Level:   logrus.WarnLevel,
+			Time:    now,
+			Message: "hello",
+			Data: logrus.Fields{
+				ChannelKey:      "payment",
+				"n":             1,
+				"user":          map[string]interface{}{"id": "u1"},
+				TraceIDKey:      "4bf92f3577b34da6a3ce929d0e0e4736",
+				SpanIDKey:       "00f067aa0ba902b7",
+				logrus.ErrorKey: errors.New("boom"),
+			},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		rl := jsoniter.Get(data, "resourceLogs", 0)
+		if v := otlpAttribute(rl.Get("resource", "attributes"), "service.name").Get("stringValue").ToString(); v != "svc" {
+			t.Fatalf(`Format() resource "service.name", Expected=%q, Actual=%q`, "svc", v)
+		}
+		if v := otlpAttribute(rl.Get("resource", "attributes"), "deployment.environment").Get("stringValue").ToString(); v != "prod" {
+			t.Fatalf(`Format() resource "deployment.environment", Expected=%q, Actual=%q`, "prod", v)
+		}
+
+		record := rl.Get("scopeLogs", 0, "logRecords", 0)
+		cases := []struct {
+			actual   string
+			expected string
+		}{
+			{actual: record.Get("timeUnixNano").ToString(), expected: "1565576028000000005"},
+			{actual: record.Get("severityNumber").ToString(), expected: "13"},
+			{actual: record.Get("severityText").ToString(), expected: "WARN"},
+			{actual: record.Get("body", "stringValue").ToString(), expected: "hello"},
+			{actual: record.Get("traceId").ToString(), expected: "4bf92f3577b34da6a3ce929d0e0e4736"},
+			{actual: record.Get("spanId").ToString(), expected: "00f067aa0ba902b7"},
+			{actual: otlpAttribute(record.Get("attributes"), "channel").Get("stringValue").ToString(), expected: "payment"},
+			{actual: otlpAttribute(record.Get("attributes"), "n").Get("intValue").ToString(), expected: "1"},
+			{actual: otlpAttribute(record.Get("attributes"), "exception.message").Get("stringValue").ToString(), expected: "boom"},
+			{actual: otlpAttribute(record.Get("attributes"), "user").Get("kvlistValue", "values", 0, "key").ToString(), expected: "id"},
+		}
+		for i, c := range cases {
+			if c.actual != c.expected {
+				t.Fatalf("Format() output case %d, Expected=%q, Actual=%q", i, c.expected, c.actual)
+			}
+		}
+	})
+
+	t.Run("HTTPRequestV1", func(t *testing.T) {
+		f := &HTTPRequestV1Formatter{TimeLayout: time.RFC3339, Encoding: EncodingOTLP}
+		headers := http.Header{}
+		headers.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		data, err := f.Format(&logrus.Entry{
+			Level: logrus.InfoLevel,
+			Time:  now,
+			Data: logrus.Fields{
+				HTTPRequestReqKey: &http.Request{
+					RemoteAddr: "1.2.3.4:1234",
+					Header:     headers,
+					Method:     http.MethodGet,
+					URL:        &url.URL{Path: "/api"},
+				},
+				HTTPRequestStatusKey: 404,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		record := jsoniter.Get(data, "resourceLogs", 0, "scopeLogs", 0, "logRecords", 0)
+		cases := []struct {
+			actual   string
+			expected string
+		}{
+			{actual: record.Get("body", "stringValue").ToString(), expected: "GET /api"},
+			{actual: record.Get("traceId").ToString(), expected: "4bf92f3577b34da6a3ce929d0e0e4736"},
+			{actual: otlpAttribute(record.Get("attributes"), "http.request.method").Get("stringValue").ToString(), expected: "GET"},
+			{actual: otlpAttribute(record.Get("attributes"), "url.path").Get("stringValue").ToString(), expected: "/api"},
+			{actual: otlpAttribute(record.Get("attributes"), "client.address").Get("stringValue").ToString(), expected: "1.2.3.4"},
+			{actual: otlpAttribute(record.Get("attributes"), "http.response.status_code").Get("intValue").ToString(), expected: "404"},
+		}
+		for i, c := range cases {
+			if c.actual != c.expected {
+				t.Fatalf("Format() output case %d, Expected=%q, Actual=%q", i, c.expected, c.actual)
+			}
+		}
+	})
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var mu sync.Mutex
+	var bodies [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("OTLP request Content-Type, Expected=%q, Actual=%q", "application/json", ct)
+		}
+		if v := r.Header.Get("X-Token"); v != "t" {
+			t.Errorf("OTLP request header, Expected=%q, Actual=%q", "t", v)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	e, err := NewOTLPExporter(srv.URL, APPLogsV1,
+		WithService("svc"),
+		WithOTLPHeaders(map[string]string{"X-Token": "t"}),
+		WithOTLPBatchSize(2),
+		WithOTLPFlushInterval(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("NewOTLPExporter() error, Expected=nil, Actual=%q", err.Error())
+	}
+
+	l, _ := NewLogger(APPLogsV1, WithOutput(ioutil.Discard), WithHooks(e))
+	for i := 0; i < 3; i++ {
+		l.Infof("log %d", i)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close() error, Expected=nil, Actual=%q", err.Error())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	total := 0
+	for _, body := range bodies {
+		rl := jsoniter.Get(body, "resourceLogs", 0)
+		if v := otlpAttribute(rl.Get("resource", "attributes"), "service.name").Get("stringValue").ToString(); v != "svc" {
+			t.Fatalf(`OTLP request resource "service.name", Expected=%q, Actual=%q`, "svc", v)
+		}
+		n := rl.Get("scopeLogs", 0, "logRecords").Size()
+		if n > 2 {
+			t.Fatalf("OTLP request batch size, Expected<=2, Actual=%d", n)
+		}
+		total += n
+	}
+	if total != 3 {
+		t.Fatalf("OTLP exported records, Expected=3, Actual=%d", total)
+	}
+
+	srv.Close()
+	l.Info("lost")
+	if err := e.Flush(); err == nil {
+		t.Fatal("Flush() error, Expected export error, Actual=nil")
+	}
+
+	// 第一批发送失败时继续发送其余批次
+	var fmu sync.Mutex
+	var posts int
+	records := 0
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		fmu.Lock()
+		defer fmu.Unlock()
+		if posts++; posts == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		records += jsoniter.Get(body, "resourceLogs", 0, "scopeLogs", 0, "logRecords").Size()
+	}))
+	defer failing.Close()
+
+	e, _ = NewOTLPExporter(failing.URL, APPLogsV1, WithOTLPBatchSize(2), WithOTLPFlushInterval(time.Hour))
+	defer e.Close()
+	l, _ = NewLogger(APPLogsV1, WithOutput(ioutil.Discard), WithHooks(e))
+	for i := 0; i < 5; i++ {
+		l.Infof("log %d", i)
+	}
+	if err := e.Flush(); err == nil {
+		t.Fatal("Flush() error, Expected export error, Actual=nil")
+	}
+	fmu.Lock()
+	defer fmu.Unlock()
+	if posts != 3 || records != 3 {
+		t.Fatalf("Flush() after failed batch, Expected=3 requests and 3 records, Actual=%d requests and %d records", posts, records)
+	}
+
+	// 无法以json编码的值替换为占位符，不影响同一批次的其他日志
+	var nmu sync.Mutex
+	var batches [][]byte
+	nan := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		nmu.Lock()
+		defer nmu.Unlock()
+		batches = append(batches, body)
+	}))
+	defer nan.Close()
+
+	e, _ = NewOTLPExporter(nan.URL, APPLogsV1, WithOTLPFlushInterval(time.Hour))
+	l, _ = NewLogger(APPLogsV1, WithOutput(ioutil.Discard), WithHooks(e))
+	l.Info("before")
+	l.WithField("v", math.NaN()).Info("nan")
+	l.Info("after")
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close() error, Expected=nil, Actual=%q", err.Error())
+	}
+	nmu.Lock()
+	defer nmu.Unlock()
+	if len(batches) != 1 {
+		t.Fatalf("OTLP requests with NaN, Expected=1, Actual=%d", len(batches))
+	}
+	logRecords := jsoniter.Get(batches[0], "resourceLogs", 0, "scopeLogs", 0, "logRecords")
+	if n := logRecords.Size(); n != 3 {
+		t.Fatalf("OTLP exported records with NaN, Expected=3, Actual=%d", n)
+	}
+	if v := otlpAttribute(logRecords.Get(1, "attributes"), "v").Get("stringValue").ToString(); v != encodePlaceholder("float64", "unsupported value: NaN") {
+		t.Fatalf(`OTLP attribute "v", Expected=%q, Actual=%q`, encodePlaceholder("float64", "unsupported value: NaN"), v)
+	}
+}
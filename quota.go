@@ -0,0 +1,212 @@
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// QuotaLinesKey 配额告警日志中每个窗口的条数配额，输出在ctx中
+	QuotaLinesKey = "quota_lines"
+	// QuotaBytesKey 配额告警日志中每个窗口的字节数配额，输出在ctx中
+	QuotaBytesKey = "quota_bytes"
+	// QuotaWindowKey 配额告警日志中的配额窗口，输出在ctx中
+	QuotaWindowKey = "quota_window"
+
+	// DefaultQuotaWindow 默认的配额窗口
+	DefaultQuotaWindow = time.Minute
+
+	quotaExceededMessage = "log quota exceeded"
+)
+
+var _ logrus.Formatter = (*QuotaFormatter)(nil)
+
+// Quota 每个窗口内允许输出的日志条数与字节数，为0时不限制
+type Quota struct {
+	Lines int
+	Bytes int
+}
+
+func (q Quota) exceeded(lines, bytes int) bool {
+	return q.Lines > 0 && lines >= q.Lines || q.Bytes > 0 && bytes >= q.Bytes
+}
+
+// Quotas 按channel设置的日志配额
+type Quotas struct {
+	// 配额窗口，从窗口内第一条日志开始计算，默认DefaultQuotaWindow
+	Window time.Duration
+	// 各channel的配额，key为channel，缺少channel的日志对应""
+	Channels map[string]Quota
+	// 未在Channels中设置的channel的配额
+	Default Quota
+	// 记录各channel的累计计数，为nil时不记录
+	Counters *QuotaCounters
+}
+
+func (q Quotas) window() time.Duration {
+	if q.Window <= 0 {
+		return DefaultQuotaWindow
+	}
+
+	return q.Window
+}
+
+func (q Quotas) quota(channel string) Quota {
+	if quota, ok := q.Channels[channel]; ok {
+		return quota
+	}
+
+	return q.Default
+}
+
+// QuotaStats channel的日志配额累计计数
+type QuotaStats struct {
+	// 输出的日志条数与字节数，不包括配额告警日志
+	Lines int64
+	Bytes int64
+	// 超出配额被丢弃的日志条数
+	DroppedLines int64
+	// 超出配额的窗口数
+	Exceeded int64
+}
+
+// QuotaCounters 各channel的日志配额累计计数，可定期读取并上报到监控系统
+type QuotaCounters struct {
+	mu    sync.Mutex
+	stats map[string]*QuotaStats
+}
+
+// NewQuotaCounters 创建日志配额计数
+func NewQuotaCounters() *QuotaCounters {
+	return &QuotaCounters{stats: make(map[string]*QuotaStats)}
+}
+
+// Stats 各channel的累计计数，key为channel
+func (qc *QuotaCounters) Stats() map[string]QuotaStats {
+	qc.mu.Lock()
+	defer qc.mu.Unlock()
+
+	stats := make(map[string]QuotaStats, len(qc.stats))
+	for channel, s := range qc.stats {
+		stats[channel] = *s
+	}
+
+	return stats
+}
+
+func (qc *QuotaCounters) add(channel string, delta QuotaStats) {
+	if qc == nil {
+		return
+	}
+
+	qc.mu.Lock()
+	s, ok := qc.stats[channel]
+	if !ok {
+		s = &QuotaStats{}
+		qc.stats[channel] = s
+	}
+	s.Lines += delta.Lines
+	s.Bytes += delta.Bytes
+	s.DroppedLines += delta.DroppedLines
+	s.Exceeded += delta.Exceeded
+	qc.mu.Unlock()
+}
+
+type quotaCounter struct {
+	start    time.Time
+	lines    int
+	bytes    int
+	exceeded bool
+}
+
+// QuotaFormatter 按channel限制日志输出量的格式化对象，超出配额的日志在格式化之前被丢弃。
+// 字节数在格式化之后累计，因此窗口内最后一条输出的日志可能使字节数超出配额；
+// 每个窗口内第一次超出配额时输出一条warning级别的告警日志，告警日志格式化失败(如http.request.v1日志)时不输出告警；logrus的hook在格式化之前执行，被丢弃的日志仍会触发hook
+type QuotaFormatter struct {
+	Formatter logrus.Formatter
+	Quotas    Quotas
+
+	mu       sync.Mutex
+	counters map[string]*quotaCounter
+}
+
+// NewQuotaFormatter 创建按channel限制f的输出量的格式化对象，可通过logger.SetFormatter包装已有的日志对象
+func NewQuotaFormatter(f logrus.Formatter, q Quotas) *QuotaFormatter {
+	return &QuotaFormatter{
+		Formatter: f,
+		Quotas:    q,
+		counters:  make(map[string]*quotaCounter),
+	}
+}
+
+// Format implements logrus.Formatter interface
+func (qf *QuotaFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	channel, _ := entry.Data[ChannelKey].(string)
+	quota := qf.Quotas.quota(channel)
+
+	qf.mu.Lock()
+	if qf.counters == nil {
+		qf.counters = make(map[string]*quotaCounter)
+	}
+	c, ok := qf.counters[channel]
+	if !ok {
+		c = &quotaCounter{start: entry.Time}
+		qf.counters[channel] = c
+	}
+	if entry.Time.Sub(c.start) >= qf.Quotas.window() {
+		c.start, c.lines, c.bytes, c.exceeded = entry.Time, 0, 0, false
+	}
+	if quota.exceeded(c.lines, c.bytes) {
+		warn := !c.exceeded
+		c.exceeded = true
+		qf.mu.Unlock()
+
+		delta := QuotaStats{DroppedLines: 1}
+		if warn {
+			delta.Exceeded = 1
+		}
+		qf.Quotas.Counters.add(channel, delta)
+		if !warn {
+			return nil, nil
+		}
+		// 告警日志格式化失败(如http.request.v1日志缺少request)时不输出告警
+		return formatSummaries(qf.Formatter, []*logrus.Entry{qf.warning(entry, channel, quota)}), nil
+	}
+	qf.mu.Unlock()
+
+	output, err := qf.Formatter.Format(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	qf.mu.Lock()
+	c.lines++
+	c.bytes += len(output)
+	qf.mu.Unlock()
+	qf.Quotas.Counters.add(channel, QuotaStats{Lines: 1, Bytes: int64(len(output))})
+
+	return output, nil
+}
+
+// warning 超出配额的告警日志
+func (qf *QuotaFormatter) warning(entry *logrus.Entry, channel string, quota Quota) *logrus.Entry {
+	data := logrus.Fields{QuotaWindowKey: qf.Quotas.window().String()}
+	if channel != "" {
+		data[ChannelKey] = channel
+	}
+	if quota.Lines > 0 {
+		data[QuotaLinesKey] = quota.Lines
+	}
+	if quota.Bytes > 0 {
+		data[QuotaBytesKey] = quota.Bytes
+	}
+
+	return &logrus.Entry{
+		Data:    data,
+		Time:    entry.Time,
+		Level:   logrus.WarnLevel,
+		Message: quotaExceededMessage,
+	}
+}
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+func TestQuotaFormatter(t *testing.T) {
+	now := time.Date(2019, 8, 12, 10, 13, 48, 0, time.UTC)
+	counters := NewQuotaCounters()
+	f, _ := NewFormatter(APPLogsV1, WithSortedKeys())
+	qf := NewQuotaFormatter(f, Quotas{
+		Window:   time.Minute,
+		Channels: map[string]Quota{"noisy": {Lines: 2}, "big": {Bytes: 150}},
+		Counters: counters,
+	})
+
+	var output []string
+	format := func(channel string, at time.Duration) {
+		data, err := qf.Format(&logrus.Entry{
+			Level:   logrus.InfoLevel,
+			Time:    now.Add(at),
+			Message: "hello",
+			Data:    logrus.Fields{ChannelKey: channel},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+		if len(data) > 0 {
+			output = append(output, strings.TrimSuffix(string(data), "\n"))
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		format("noisy", time.Duration(i)*time.Second)
+		format("big", time.Duration(i)*time.Second)
+		format("other", time.Duration(i)*time.Second)
+	}
+	format("noisy", time.Minute)
+
+	counts := map[string]int{}
+	for _, o := range output {
+		counts[jsoniter.Get([]byte(o), "channel").ToString()+"/"+jsoniter.Get([]byte(o), "level").ToString()]++
+	}
+	// noisy在第一个窗口输出2条及1条告警，第二个窗口输出1条；big每条日志约100字节，输出2条后输出1条告警
+	expectedCounts := map[string]int{"noisy/info": 3, "noisy/warning": 1, "big/info": 2, "big/warning": 1, "other/info": 5}
+	for k, n := range expectedCounts {
+		if counts[k] != n {
+			t.Fatalf("Format() lines of %s, Expected=%d, Actual=%d (%q)", k, n, counts[k], output)
+		}
+	}
+
+	warning := `{"schema":"app.logs.v1","channel":"noisy","level":"warning","time":"2019-08-12T10:13:50Z","msg":"log quota exceeded","ctx":{"quota_lines":2,"quota_window":"1m0s"}}`
+	if output[6] != warning {
+		t.Fatalf("Format() warning, Expected=%q, Actual=%q", warning, output[6])
+	}
+
+	stats := counters.Stats()
+	if s := stats["noisy"]; s.Lines != 3 || s.DroppedLines != 3 || s.Exceeded != 1 {
+		t.Fatalf("Stats() of noisy, Expected={Lines:3 DroppedLines:3 Exceeded:1}, Actual=%+v", s)
+	}
+	if s := stats["other"]; s.Lines != 5 || s.Bytes != int64(5*(len(output[2])+1)) || s.DroppedLines != 0 {
+		t.Fatalf("Stats() of other, Expected={Lines:5 Bytes:%d}, Actual=%+v", 5*(len(output[2])+1), s)
+	}
+
+	// 通过NewLogger限制
+	buf := &bytes.Buffer{}
+	l, _ := NewLogger(APPLogsV1, WithOutput(buf), WithQuotas(Quotas{Default: Quota{Lines: 1}}))
+	for i := 0; i < 3; i++ {
+		l.Info("hello")
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Fatalf("NewLogger() with quotas lines, Expected=2, Actual=%d", n)
+	}
+
+	// http.request.v1日志无法输出告警日志，超出配额的日志直接丢弃
+	hf, _ := NewFormatter(HTTPRequestV1)
+	hqf := NewQuotaFormatter(hf, Quotas{Default: Quota{Lines: 1}})
+	req := &http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/"}}
+	for i := 0; i < 3; i++ {
+		data, err := hqf.Format(&logrus.Entry{Level: logrus.InfoLevel, Time: now, Data: logrus.Fields{HTTPRequestReqKey: req}})
+		if err != nil {
+			t.Fatalf("Format() of http.request.v1 error, Expected=nil, Actual=%q", err.Error())
+		}
+		if expected := i == 0; (len(data) > 0) != expected {
+			t.Fatalf("Format() of http.request.v1 output %d, Expected=%v, Actual=%q", i, expected, data)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultRequestBufferSize 默认每个请求缓存的日志条数
+const DefaultRequestBufferSize = 100
+
+type requestBufferKey struct{}
+
+type bufferedEntry struct {
+	logger *logrus.Logger
+	fields logrus.Fields
+	level  logrus.Level
+	msg    string
+	time   time.Time
+}
+
+// RequestBuffer 单个请求的日志环形缓冲区，通过SugaredLogger、Channel的WithContext记录的日志中，
+// 级别为Level及更详细的日志被缓存而不输出，超出容量时覆盖最早的日志。
+// 记录error及更严重级别的日志或调用Flush时，按顺序以原本的时间输出缓存的日志，之后的日志不再缓存；
+// 调用Discard丢弃缓存的日志。缓存与输出缓存的日志时不按日志对象(包括Channel)的级别过滤，日志对象只开启info级别时
+// 缓存的debug日志也会输出。直接通过logrus记录的日志不会被缓存，也不会输出缓存的日志
+type RequestBuffer struct {
+	mu      sync.Mutex
+	level   logrus.Level
+	entries []bufferedEntry
+	next    int
+	full    bool
+	done    bool
+}
+
+// NewRequestBuffer 创建最多缓存size条日志的缓冲区，size小于等于0时为DefaultRequestBufferSize
+func NewRequestBuffer(size int, level logrus.Level) *RequestBuffer {
+	if size <= 0 {
+		size = DefaultRequestBufferSize
+	}
+
+	return &RequestBuffer{
+		level:   level,
+		entries: make([]bufferedEntry, size),
+	}
+}
+
+// ContextWithRequestBuffer 将缓冲区附加到请求的context
+func ContextWithRequestBuffer(ctx context.Context, b *RequestBuffer) context.Context {
+	return context.WithValue(ctx, requestBufferKey{}, b)
+}
+
+// RequestBufferFromContext 获得附加到context的缓冲区，不存在时返回nil
+func RequestBufferFromContext(ctx context.Context) *RequestBuffer {
+	b, _ := ctx.Value(requestBufferKey{}).(*RequestBuffer)
+	return b
+}
+
+// holds 是否缓存该级别的日志，b为nil时返回false
+func (b *RequestBuffer) holds(level logrus.Level) bool {
+	return b != nil && level >= b.level
+}
+
+// hold 缓存级别为Level及更详细的日志，已Flush或Discard时不再缓存
+func (b *RequestBuffer) hold(l *logrus.Logger, fields logrus.Fields, level logrus.Level, msg string) bool {
+	if !b.holds(level) {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.done {
+		return false
+	}
+	b.entries[b.next] = bufferedEntry{logger: l, fields: fields, level: level, msg: msg, time: time.Now()}
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+
+	return true
+}
+
+// take 按记录顺序取出缓存的日志并停止缓存
+func (b *RequestBuffer) take() []bufferedEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var entries []bufferedEntry
+	if b.full {
+		entries = append(entries, b.entries[b.next:]...)
+	}
+	entries = append(entries, b.entries[:b.next]...)
+	b.entries = nil
+	b.next = 0
+	b.full = false
+	b.done = true
+
+	return entries
+}
+
+// Flush 按顺序以原本的时间输出缓存的日志，之后的日志不再缓存
+func (b *RequestBuffer) Flush() {
+	if b == nil {
+		return
+	}
+
+	for _, e := range b.take() {
+		e.write()
+	}
+}
+
+// write 不按日志对象的级别过滤，执行hook后经日志对象的格式化对象写入Out。
+// logrus v1.4.2没有公开日志对象的锁，与FlushSummaries相同直接写入，Out需要支持并发写入(如os.Stdout)
+func (e bufferedEntry) write() {
+	entry := logrus.NewEntry(e.logger)
+	entry.Data = e.fields
+	entry.Time = e.time
+	entry.Level = e.level
+	entry.Message = e.msg
+	if err := e.logger.Hooks.Fire(e.level, entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to fire hook: %v\n", err)
+	}
+
+	output, err := e.logger.Formatter.Format(entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to obtain reader, %v\n", err)
+		return
+	}
+	if _, err := e.logger.Out.Write(output); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+	}
+}
+
+// Discard 丢弃缓存的日志，之后的日志不再缓存
+func (b *RequestBuffer) Discard() {
+	if b == nil {
+		return
+	}
+
+	b.take()
+}
+
+// RequestBufferMiddleware 为每个请求附加最多缓存size条日志的缓冲区，
+// 响应状态码为5xx或处理请求时panic时输出缓存的日志，否则丢弃
+func RequestBufferMiddleware(size int, level logrus.Level) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b := NewRequestBuffer(size, level)
+			sw := &statusWriter{ResponseWriter: w}
+			defer func() {
+				if p := recover(); p != nil {
+					b.Flush()
+					panic(p)
+				}
+				if sw.status >= http.StatusInternalServerError {
+					b.Flush()
+				} else {
+					b.Discard()
+				}
+			}()
+
+			next.ServeHTTP(sw, r.WithContext(ContextWithRequestBuffer(r.Context(), b)))
+		})
+	}
+}
+
+var (
+	_ http.Flusher  = (*statusWriter)(nil)
+	_ http.Hijacker = (*statusWriter)(nil)
+	_ http.Pusher   = (*statusWriter)(nil)
+)
+
+// statusWriter 记录响应状态码，并转发原ResponseWriter的Flusher、Hijacker、Pusher
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher interface，原ResponseWriter不支持时不处理
+func (w *statusWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker interface
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("http.Hijacker not supported")
+	}
+	return h.Hijack()
+}
+
+// Push implements http.Pusher interface
+func (w *statusWriter) Push(target string, opts *http.PushOptions) error {
+	p, ok := w.ResponseWriter.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+	return p.Push(target, opts)
+}
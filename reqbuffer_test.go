@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRequestBuffer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, _ := NewLogger(APPLogsV1, WithOutput(buf), WithLevel(logrus.DebugLevel))
+
+	handler := func(status int, logs func(s *SugaredLogger)) http.Handler {
+		return RequestBufferMiddleware(2, logrus.DebugLevel)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logs(Sugar(l).WithContext(r.Context()))
+			w.WriteHeader(status)
+		}))
+	}
+	serve := func(h http.Handler) string {
+		buf.Reset()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		var msgs []string
+		for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			if line != "" {
+				msgs = append(msgs, jsoniter.Get([]byte(line), "level").ToString()+" "+jsoniter.Get([]byte(line), "msg").ToString())
+			}
+		}
+		return strings.Join(msgs, ",")
+	}
+
+	cases := []struct {
+		name     string
+		status   int
+		logs     func(s *SugaredLogger)
+		expected string
+	}{
+		{
+			name:   "Success",
+			status: http.StatusOK,
+			logs: func(s *SugaredLogger) {
+				s.Debugw("d1")
+				s.Infow("i1")
+				s.Channel("db").Debugw("d2")
+			},
+			expected: "info i1",
+		},
+		{
+			name:   "ServerError",
+			status: http.StatusBadGateway,
+			logs: func(s *SugaredLogger) {
+				s.Debugw("d1")
+				s.Infow("i1")
+				s.Channel("db").Debugw("d2")
+				s.Debugw("d3")
+			},
+			expected: "info i1,debug d2,debug d3",
+		},
+		{
+			name:   "ErrorEntry",
+			status: http.StatusBadRequest,
+			logs: func(s *SugaredLogger) {
+				s.Debugw("d1")
+				s.Errorw("e1")
+				s.Debugw("d2")
+			},
+			expected: "debug d1,error e1,debug d2",
+		},
+	}
+	for _, c := range cases {
+		if actual := serve(handler(c.status, c.logs)); actual != c.expected {
+			t.Fatalf("%s output, Expected=%q, Actual=%q", c.name, c.expected, actual)
+		}
+	}
+
+	// 缓存的日志保留原本的时间
+	b := NewRequestBuffer(10, logrus.DebugLevel)
+	s := Sugar(l).WithContext(ContextWithRequestBuffer(httptest.NewRequest(http.MethodGet, "/", nil).Context(), b))
+	buf.Reset()
+	s.Debugw("held")
+	held := b.entries[0].time
+	b.Flush()
+	if actual, expected := jsoniter.Get(buf.Bytes(), "time").ToString(), held.Format("2006-01-02T15:04:05Z07:00"); actual != expected {
+		t.Fatalf("Flush() time, Expected=%q, Actual=%q", expected, actual)
+	}
+
+	// 日志对象只开启info级别时仍缓存并输出debug日志，未缓存的debug日志不输出
+	info, _ := NewLogger(APPLogsV1, WithOutput(buf), WithLevel(logrus.InfoLevel))
+	b = NewRequestBuffer(10, logrus.DebugLevel)
+	s = Sugar(info).WithContext(ContextWithRequestBuffer(httptest.NewRequest(http.MethodGet, "/", nil).Context(), b))
+	buf.Reset()
+	s.Debugw("d1")
+	s.Channel("db").Debugw("d2")
+	s.Errorw("e1")
+	s.Debugw("d3")
+	var msgs []string
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		msgs = append(msgs, jsoniter.Get([]byte(line), "level").ToString()+" "+jsoniter.Get([]byte(line), "msg").ToString())
+	}
+	if actual, expected := strings.Join(msgs, ","), "debug d1,debug d2,error e1"; actual != expected {
+		t.Fatalf("info logger output, Expected=%q, Actual=%q", expected, actual)
+	}
+
+	// panic时输出缓存的日志
+	panicking := RequestBufferMiddleware(0, logrus.DebugLevel)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Sugar(l).WithContext(r.Context()).Debugw("before panic")
+		panic("boom")
+	}))
+	buf.Reset()
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Fatalf("ServeHTTP() panic, Expected=%q, Actual=%v", "boom", p)
+			}
+		}()
+		panicking.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	if actual := jsoniter.Get(buf.Bytes(), "msg").ToString(); actual != "before panic" {
+		t.Fatalf("panic output, Expected=%q, Actual=%q", "before panic", actual)
+	}
+
+	// 转发原ResponseWriter的http.Flusher等接口
+	rec := httptest.NewRecorder()
+	RequestBufferMiddleware(0, logrus.DebugLevel)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		if _, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			t.Fatal("Hijack() error, Expected not supported, Actual=nil")
+		}
+		if err := w.(http.Pusher).Push("/app.js", nil); err != http.ErrNotSupported {
+			t.Fatalf("Push() error, Expected=%q, Actual=%v", http.ErrNotSupported, err)
+		}
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !rec.Flushed {
+		t.Fatal("Flush() of ResponseRecorder, Expected=true, Actual=false")
+	}
+}
@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"net/http"
+	"path"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SampleRateKey http.request.v1日志被保留的比例，输出在extra中，统计请求数时可用1/sample_rate加权
+const SampleRateKey = "sample_rate"
+
+var _ logrus.Formatter = (*RequestSamplingFormatter)(nil)
+
+// RequestSampleRule http.request.v1日志的采样规则
+type RequestSampleRule struct {
+	// 请求方法，为空时匹配全部方法
+	Method string
+	// 路径模式，path.Match语法，如"/api/feed"、"/api/*/items"，为空时匹配全部路径
+	Path string
+	// 状态码类别，如2表示2xx，为0时匹配全部状态码
+	StatusClass int
+	// 保留的比例，大于等于1时全部保留，小于等于0时全部丢弃
+	Rate float64
+}
+
+func (r RequestSampleRule) match(req *http.Request, status int64) bool {
+	if r.StatusClass > 0 && status/100 != int64(r.StatusClass) {
+		return false
+	}
+
+	return matchRoute(r.Method, r.Path, req)
+}
+
+// matchRoute 请求是否匹配方法与路径模式，为空时匹配全部
+func matchRoute(method, pattern string, req *http.Request) bool {
+	if method != "" && method != req.Method {
+		return false
+	}
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, req.URL.Path)
+
+	return ok
+}
+
+// RequestSampling http.request.v1日志的采样规则
+type RequestSampling struct {
+	// 按顺序使用第一条匹配的规则，没有匹配的规则时全部保留
+	Rules []RequestSampleRule
+	// 不记录日志的路径模式，如"/healthz"
+	ExcludePaths []string
+}
+
+// RequestSamplingFormatter 对http.request.v1日志采样的格式化对象，被丢弃的日志不会被格式化与输出，
+// 保留的日志在extra中记录sample_rate。按比例均匀保留，如Rate为0.01时保留匹配规则的第1、101、201...条日志；
+// 标记slow的慢请求与warning及以上级别的日志不参与采样，ExcludePaths中的路径仍不记录
+type RequestSamplingFormatter struct {
+	Formatter logrus.Formatter
+	Sampling  RequestSampling
+
+	mu sync.Mutex
+	// 各规则的累计比例，达到1时保留一条日志
+	acc map[int]float64
+}
+
+// NewRequestSamplingFormatter 创建对f输出的http.request.v1日志采样的格式化对象
+func NewRequestSamplingFormatter(f logrus.Formatter, s RequestSampling) *RequestSamplingFormatter {
+	return &RequestSamplingFormatter{
+		Formatter: f,
+		Sampling:  s,
+		acc:       make(map[int]float64),
+	}
+}
+
+// Format implements logrus.Formatter interface
+func (rf *RequestSamplingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	req, err := httpRequest(entry)
+	if err != nil {
+		return rf.Formatter.Format(entry)
+	}
+
+	for _, p := range rf.Sampling.ExcludePaths {
+		if matchRoute("", p, req) {
+			return nil, nil
+		}
+	}
+
+	rate := 1.0
+	status, _ := intValue(entry.Data[HTTPRequestStatusKey])
+	for i, r := range rf.Sampling.Rules {
+		if !r.match(req, status) {
+			continue
+		}
+		if rf.keep(entry) {
+			break
+		}
+		if !rf.sample(i, r.Rate) {
+			return nil, nil
+		}
+		if r.Rate < 1 {
+			rate = r.Rate
+		}
+		break
+	}
+
+	e := *entry
+	e.Data = make(logrus.Fields, len(entry.Data)+1)
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+	e.Data[SampleRateKey] = rate
+
+	return rf.Formatter.Format(&e)
+}
+
+// keep 慢请求与warning及以上级别的日志不参与采样，全部保留
+func (rf *RequestSamplingFormatter) keep(entry *logrus.Entry) bool {
+	if slow, _ := entry.Data[SlowKey].(bool); slow {
+		return true
+	}
+
+	return entry.Level <= logrus.WarnLevel
+}
+
+// sample 按比例决定是否保留第i条规则匹配的日志
+func (rf *RequestSamplingFormatter) sample(i int, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.acc == nil {
+		rf.acc = make(map[int]float64)
+	}
+	acc, ok := rf.acc[i]
+	if !ok {
+		acc = 1
+	}
+	// 容忍累加比例时的浮点误差
+	keep := acc >= 1-1e-9
+	if keep {
+		acc--
+	}
+	rf.acc[i] = acc + rate
+
+	return keep
+}
@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRequestSamplingFormatter(t *testing.T) {
+	f, _ := NewFormatter(HTTPRequestV1)
+	rf := NewRequestSamplingFormatter(f, RequestSampling{
+		Rules: []RequestSampleRule{
+			{Method: http.MethodGet, Path: "/api/feed", StatusClass: 2, Rate: 0.1},
+			{Path: "/api/*/items", Rate: 0},
+		},
+		ExcludePaths: []string{"/healthz"},
+	})
+
+	count := func(method, path string, status, n int) (int, string) {
+		kept, rate := 0, ""
+		for i := 0; i < n; i++ {
+			data, err := rf.Format(&logrus.Entry{
+				Level: logrus.InfoLevel,
+				Data: logrus.Fields{
+					HTTPRequestReqKey:    &http.Request{Method: method, URL: &url.URL{Path: path}},
+					HTTPRequestStatusKey: status,
+				},
+			})
+			if err != nil {
+				t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+			}
+			if len(data) > 0 {
+				kept++
+				rate = jsoniter.Get(data, "extra", SampleRateKey).ToString()
+			}
+		}
+		return kept, rate
+	}
+
+	cases := []struct {
+		method string
+		path   string
+		status int
+		kept   int
+		rate   string
+	}{
+		{method: http.MethodGet, path: "/api/feed", status: 200, kept: 10, rate: "0.1"},
+		{method: http.MethodGet, path: "/api/feed", status: 500, kept: 100, rate: "1"},
+		{method: http.MethodPost, path: "/api/feed", status: 200, kept: 100, rate: "1"},
+		{method: http.MethodGet, path: "/api/1/items", status: 200, kept: 0},
+		{method: http.MethodGet, path: "/healthz", status: 200, kept: 0},
+	}
+	for _, c := range cases {
+		kept, rate := count(c.method, c.path, c.status, 100)
+		if kept != c.kept || rate != c.rate {
+			t.Fatalf("Format() %s %s %d kept, Expected=%d rate=%q, Actual=%d rate=%q", c.method, c.path, c.status, c.kept, c.rate, kept, rate)
+		}
+	}
+
+	// 慢请求与warning级别的日志不参与采样
+	for _, entry := range []*logrus.Entry{
+		{Level: logrus.InfoLevel, Data: logrus.Fields{SlowKey: true}},
+		{Level: logrus.WarnLevel, Data: logrus.Fields{}},
+	} {
+		entry.Data[HTTPRequestReqKey] = &http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/api/1/items"}}
+		data, err := rf.Format(entry)
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+		if rate := jsoniter.Get(data, "extra", SampleRateKey).ToString(); rate != "1" {
+			t.Fatalf("Format() %s entry rate, Expected=%q, Actual=%q (%q)", entry.Level, "1", rate, data)
+		}
+	}
+
+	// 通过NewLogger采样，慢请求不会被采样丢弃
+	slow := &bytes.Buffer{}
+	l, _ := NewLogger(HTTPRequestV1, WithOutput(slow),
+		WithRequestSampling(RequestSampling{Rules: []RequestSampleRule{{Rate: 0}}}),
+		WithSlowRequests(SlowRequests{Default: time.Second}),
+	)
+	for _, latency := range []time.Duration{time.Millisecond, 2 * time.Second} {
+		l.WithFields(logrus.Fields{
+			HTTPRequestReqKey:     &http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/api"}},
+			HTTPRequestLatencyKey: latency,
+		}).Info()
+	}
+	if n := strings.Count(slow.String(), "\n"); n != 1 || !jsoniter.Get(slow.Bytes(), "extra", SlowKey).ToBool() {
+		t.Fatalf("NewLogger() with request sampling and slow requests, Expected=1 slow line, Actual=%q", slow.String())
+	}
+
+	// 通过NewLogger采样
+	buf := &bytes.Buffer{}
+	l, _ = NewLogger(HTTPRequestV1, WithOutput(buf), WithRequestSampling(RequestSampling{ExcludePaths: []string{"/healthz"}}))
+	for _, p := range []string{"/healthz", "/api"} {
+		l.WithField(HTTPRequestReqKey, &http.Request{Method: http.MethodGet, URL: &url.URL{Path: p}}).Info()
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 1 {
+		t.Fatalf("NewLogger() with request sampling lines, Expected=1, Actual=%d", n)
+	}
+}
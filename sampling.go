@@ -0,0 +1,131 @@
+package logger
+
+import (
+	"io"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// SampledCountKey 采样汇总日志中周期内被丢弃的日志条数，输出在ctx中
+	SampledCountKey = "sampled_count"
+	// SampleIntervalKey 采样汇总日志的计数周期，输出在ctx中
+	SampleIntervalKey = "sample_interval"
+
+	// DefaultSampleInterval 默认的采样计数周期
+	DefaultSampleInterval = time.Second
+)
+
+var _ logrus.Formatter = (*SamplingFormatter)(nil)
+
+// Sampling 日志采样规则，按(level, channel, msg)分别计数，
+// 每个周期内输出前First条，之后每Thereafter条输出1条
+type Sampling struct {
+	// 计数周期，默认DefaultSampleInterval
+	Interval time.Duration
+	First    int
+	// 为0时丢弃周期内前First条之后的全部日志
+	Thereafter int
+}
+
+func (s Sampling) interval() time.Duration {
+	if s.Interval <= 0 {
+		return DefaultSampleInterval
+	}
+
+	return s.Interval
+}
+
+type sampleKey struct {
+	level   logrus.Level
+	channel string
+	msg     string
+}
+
+type sampleCounter struct {
+	key     sampleKey
+	start   time.Time
+	count   int
+	dropped int
+}
+
+func (c *sampleCounter) started() time.Time {
+	return c.start
+}
+
+// summary 周期内有日志被丢弃时返回汇总日志，时间为周期结束的时间
+func (c *sampleCounter) summary(interval time.Duration) *logrus.Entry {
+	if c.dropped == 0 {
+		return nil
+	}
+
+	data := logrus.Fields{
+		SampledCountKey:   c.dropped,
+		SampleIntervalKey: interval.String(),
+	}
+	if c.key.channel != "" {
+		data[ChannelKey] = c.key.channel
+	}
+	return &logrus.Entry{
+		Data:    data,
+		Time:    c.start.Add(interval),
+		Level:   c.key.level,
+		Message: c.key.msg,
+	}
+}
+
+// SamplingFormatter 对日志采样的格式化对象，被丢弃的日志不会被格式化与输出，只适用于app.logs.v1日志。
+// 周期结束后，有日志被丢弃的(level, channel, msg)会在之后的第一条日志之前输出一条同样level、channel、msg的汇总日志，
+// ctx中的sampled_count为周期内被丢弃的条数，之后没有日志时可以通过FlushSummaries定时输出；
+// 汇总日志格式化失败时只丢弃汇总日志；logrus的hook在格式化之前执行，被丢弃的日志仍会触发hook
+type SamplingFormatter struct {
+	Formatter logrus.Formatter
+	Sampling  Sampling
+
+	counters windowCounters
+}
+
+// NewSamplingFormatter 创建对f的输出采样的格式化对象，可通过logger.SetFormatter包装已有的日志对象
+func NewSamplingFormatter(f logrus.Formatter, s Sampling) *SamplingFormatter {
+	return &SamplingFormatter{
+		Formatter: f,
+		Sampling:  s,
+	}
+}
+
+// Format implements logrus.Formatter interface
+func (sf *SamplingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	key := sampleKey{level: entry.Level, msg: entry.Message}
+	key.channel, _ = entry.Data[ChannelKey].(string)
+
+	var sampled bool
+	summaries := sf.counters.update(key, entry.Time, sf.Sampling.interval(), func(wc windowCounter) windowCounter {
+		c, ok := wc.(*sampleCounter)
+		if !ok {
+			c = &sampleCounter{key: key, start: entry.Time}
+		}
+		c.count++
+		sampled = c.count <= sf.Sampling.First ||
+			sf.Sampling.Thereafter > 0 && (c.count-sf.Sampling.First)%sf.Sampling.Thereafter == 0
+		if !sampled {
+			c.dropped++
+		}
+		return c
+	})
+
+	output := formatSummaries(sf.Formatter, summaries)
+	if !sampled {
+		return output, nil
+	}
+
+	b, err := sf.Formatter.Format(entry)
+	if err != nil || len(output) == 0 {
+		return b, err
+	}
+	return append(output, b...), nil
+}
+
+func (sf *SamplingFormatter) flushSummaries(w io.Writer, now time.Time, all bool) error {
+	return sf.counters.flush(sf.Formatter, w, now, sf.Sampling.interval(), all)
+}
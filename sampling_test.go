@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSamplingFormatter(t *testing.T) {
+	now := time.Date(2019, 8, 12, 10, 13, 48, 0, time.UTC)
+	f, _ := NewFormatter(APPLogsV1, WithSortedKeys())
+	sf := NewSamplingFormatter(f, Sampling{Interval: time.Second, First: 2, Thereafter: 3})
+
+	var output []string
+	format := formatLines(t, sf, &output)
+
+	format(&logrus.Entry{Level: logrus.InfoLevel, Time: now, Message: "other", Data: logrus.Fields{ChannelKey: "payment"}})
+	for i := 0; i < 10; i++ {
+		format(&logrus.Entry{Level: logrus.InfoLevel, Time: now.Add(time.Duration(i) * time.Millisecond), Message: "hot", Data: logrus.Fields{"i": i}})
+	}
+	format(&logrus.Entry{Level: logrus.InfoLevel, Time: now.Add(1500 * time.Millisecond), Message: "cold", Data: logrus.Fields{}})
+
+	expected := []string{
+		`{"schema":"app.logs.v1","channel":"payment","level":"info","time":"2019-08-12T10:13:48Z","msg":"other"}`,
+		`{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","msg":"hot","ctx":{"i":0}}`,
+		`{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","msg":"hot","ctx":{"i":1}}`,
+		`{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","msg":"hot","ctx":{"i":4}}`,
+		`{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","msg":"hot","ctx":{"i":7}}`,
+		`{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:49Z","msg":"hot","ctx":{"sample_interval":"1s","sampled_count":6}}`,
+		`{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:49Z","msg":"cold"}`,
+	}
+	if strings.Join(output, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, output)
+	}
+
+	// 通过NewLogger采样
+	if _, _, n := loggerLines(t, WithSampling(Sampling{Interval: time.Hour, First: 1})); n != 1 {
+		t.Fatalf("NewLogger() with sampling lines, Expected=1, Actual=%d", n)
+	}
+
+	// 请求日志不支持按msg采样
+	if _, err := NewLogger(HTTPRequestV1, WithSampling(Sampling{First: 1})); errors.Cause(err) != ErrOptionNotSupported {
+		t.Fatalf("NewLogger() with sampling error, Expected=%q, Actual=%v", ErrOptionNotSupported, err)
+	}
+
+	// 汇总日志格式化失败时不影响之后的日志
+	hf, _ := NewFormatter(HTTPRequestV1)
+	sf = NewSamplingFormatter(hf, Sampling{Interval: time.Second, First: 1})
+	req := &http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/"}}
+	for i, at := range []time.Duration{0, 500 * time.Millisecond, 1500 * time.Millisecond} {
+		data, err := sf.Format(&logrus.Entry{Level: logrus.InfoLevel, Time: now.Add(at), Data: logrus.Fields{HTTPRequestReqKey: req}})
+		if err != nil {
+			t.Fatalf("Format() of http.request.v1 error, Expected=nil, Actual=%q", err.Error())
+		}
+		if lines := strings.Count(string(data), "\n"); lines != 1-i%2 {
+			t.Fatalf("Format() of http.request.v1 lines, Expected=%d, Actual=%d", 1-i%2, lines)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SlowKey 慢请求标记，输出在extra中
+const SlowKey = "slow"
+
+var _ logrus.Formatter = (*SlowRequestFormatter)(nil)
+
+// RouteThreshold 路由的慢请求耗时阈值
+type RouteThreshold struct {
+	// 请求方法，为空时匹配全部方法
+	Method string
+	// 路径模式，path.Match语法，为空时匹配全部路径
+	Path      string
+	Threshold time.Duration
+}
+
+// SlowRequests 慢请求检测规则，耗时取自extra中time.Duration类型的latency
+type SlowRequests struct {
+	// 按顺序使用第一条匹配的阈值
+	Routes []RouteThreshold
+	// 没有匹配的路由时使用的阈值，为0时不检测
+	Default time.Duration
+	// 检测到慢请求时调用，如输出该请求缓存的app.logs.v1日志；
+	// 在格式化时调用，不能通过同一个日志对象记录日志
+	OnSlow func(req *http.Request, latency time.Duration)
+}
+
+func (s SlowRequests) threshold(req *http.Request) time.Duration {
+	for _, r := range s.Routes {
+		if matchRoute(r.Method, r.Path, req) {
+			return r.Threshold
+		}
+	}
+
+	return s.Default
+}
+
+// SlowRequestFormatter 检测慢请求的格式化对象，耗时超出阈值的http.request.v1日志级别提升为warning，
+// 并在extra中输出"slow": true。格式化在logrus的级别过滤与hook之后执行，需要在此之前提升级别时使用LogRequest
+type SlowRequestFormatter struct {
+	Formatter    logrus.Formatter
+	SlowRequests SlowRequests
+}
+
+// NewSlowRequestFormatter 创建检测f输出的http.request.v1日志中慢请求的格式化对象
+func NewSlowRequestFormatter(f logrus.Formatter, s SlowRequests) *SlowRequestFormatter {
+	return &SlowRequestFormatter{Formatter: f, SlowRequests: s}
+}
+
+// Format implements logrus.Formatter interface
+func (sf *SlowRequestFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	return sf.Formatter.Format(sf.detect(entry))
+}
+
+// detect 检测慢请求，是慢请求时返回提升级别并标记slow的副本，否则返回entry本身；
+// 已标记slow的日志(如通过LogRequest记录)不再重复检测
+func (sf *SlowRequestFormatter) detect(entry *logrus.Entry) *logrus.Entry {
+	if _, ok := entry.Data[SlowKey]; ok {
+		return entry
+	}
+	req, err := httpRequest(entry)
+	if err != nil {
+		return entry
+	}
+	latency, ok := entry.Data[HTTPRequestLatencyKey].(time.Duration)
+	if !ok {
+		return entry
+	}
+	threshold := sf.SlowRequests.threshold(req)
+	if threshold <= 0 || latency <= threshold {
+		return entry
+	}
+
+	if sf.SlowRequests.OnSlow != nil {
+		sf.SlowRequests.OnSlow(req, latency)
+	}
+
+	e := *entry
+	if e.Level > logrus.WarnLevel {
+		e.Level = logrus.WarnLevel
+	}
+	e.Data = make(logrus.Fields, len(entry.Data)+1)
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+	e.Data[SlowKey] = true
+
+	return &e
+}
+
+// LogRequest 记录http.request.v1日志，l的格式化对象包含SlowRequestFormatter(如通过WithSlowRequests创建)时，
+// 在logrus的级别过滤与hook之前检测慢请求并提升级别，因此日志对象为warning级别时慢请求仍会输出，
+// hook(如OTLPExporter)也能得到提升后的级别与slow标记；直接通过logrus记录时只在格式化时提升级别，日志对象需要开启info级别
+func LogRequest(l *logrus.Logger, level logrus.Level, fields logrus.Fields) {
+	entry := logrus.NewEntry(l).WithFields(fields)
+	entry.Level = level
+	for f := l.Formatter; f != nil; f = innerFormatter(f) {
+		if sf, ok := f.(*SlowRequestFormatter); ok {
+			entry = sf.detect(entry)
+			break
+		}
+	}
+
+	entry.Log(entry.Level)
+}
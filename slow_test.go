@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSlowRequestFormatter(t *testing.T) {
+	var slow []string
+	f, _ := NewFormatter(HTTPRequestV1)
+	sf := NewSlowRequestFormatter(f, SlowRequests{
+		Routes:  []RouteThreshold{{Method: http.MethodGet, Path: "/api/report", Threshold: 5 * time.Second}},
+		Default: time.Second,
+		OnSlow: func(req *http.Request, latency time.Duration) {
+			slow = append(slow, req.URL.Path+" "+latency.String())
+		},
+	})
+
+	cases := []struct {
+		level    logrus.Level
+		path     string
+		latency  interface{}
+		expected string
+		slow     string
+	}{
+		{level: logrus.InfoLevel, path: "/api/report", latency: 2 * time.Second, expected: "info", slow: ""},
+		{level: logrus.InfoLevel, path: "/api/report", latency: 6 * time.Second, expected: "warning", slow: "true"},
+		{level: logrus.InfoLevel, path: "/api/user", latency: 2 * time.Second, expected: "warning", slow: "true"},
+		{level: logrus.ErrorLevel, path: "/api/user", latency: 2 * time.Second, expected: "error", slow: "true"},
+		{level: logrus.InfoLevel, path: "/api/user", latency: 500 * time.Millisecond, expected: "info", slow: ""},
+		{level: logrus.InfoLevel, path: "/api/user", latency: 2000, expected: "info", slow: ""},
+	}
+	for _, c := range cases {
+		data, err := sf.Format(&logrus.Entry{
+			Level: c.level,
+			Data: logrus.Fields{
+				HTTPRequestReqKey:     &http.Request{Method: http.MethodGet, URL: &url.URL{Path: c.path}},
+				HTTPRequestLatencyKey: c.latency,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+		if actual := jsoniter.Get(data, "level").ToString(); actual != c.expected {
+			t.Fatalf("Format() %s %v level, Expected=%q, Actual=%q", c.path, c.latency, c.expected, actual)
+		}
+		if actual := jsoniter.Get(data, "extra", SlowKey).ToString(); actual != c.slow {
+			t.Fatalf("Format() %s %v extra.slow, Expected=%q, Actual=%q", c.path, c.latency, c.slow, actual)
+		}
+	}
+
+	if len(slow) != 3 || slow[0] != "/api/report 6s" {
+		t.Fatalf("OnSlow() calls, Expected=3, Actual=%q", slow)
+	}
+
+	// 通过NewLogger检测
+	buf := &bytes.Buffer{}
+	l, _ := NewLogger(HTTPRequestV1, WithOutput(buf), WithSlowRequests(SlowRequests{Default: time.Second}))
+	l.WithFields(logrus.Fields{
+		HTTPRequestReqKey:     &http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/"}},
+		HTTPRequestLatencyKey: 3 * time.Second,
+	}).Info()
+	if actual := jsoniter.Get(buf.Bytes(), "level").ToString(); actual != "warning" {
+		t.Fatalf("NewLogger() with slow requests level, Expected=%q, Actual=%q", "warning", actual)
+	}
+
+	// 通过LogRequest在级别过滤与hook之前提升级别
+	buf.Reset()
+	var fired []string
+	l.SetLevel(logrus.WarnLevel)
+	l.AddHook(hookFunc(func(e *logrus.Entry) error {
+		fired = append(fired, e.Level.String())
+		if e.Data[SlowKey] != true {
+			t.Fatalf("LogRequest() hook %s, Expected=true, Actual=%v", SlowKey, e.Data[SlowKey])
+		}
+		return nil
+	}))
+	for _, latency := range []time.Duration{3 * time.Second, 500 * time.Millisecond} {
+		LogRequest(l, logrus.InfoLevel, logrus.Fields{
+			HTTPRequestReqKey:     &http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/"}},
+			HTTPRequestLatencyKey: latency,
+		})
+	}
+	if actual := jsoniter.Get(buf.Bytes(), "extra", SlowKey).ToBool(); !actual || bytes.Count(buf.Bytes(), []byte("\n")) != 1 {
+		t.Fatalf("LogRequest() output, Expected=1 slow request, Actual=%q", buf.String())
+	}
+	if len(fired) != 1 || fired[0] != "warning" {
+		t.Fatalf("LogRequest() hook levels, Expected=%q, Actual=%q", []string{"warning"}, fired)
+	}
+}
+
+// hookFunc 所有级别都触发的hook
+type hookFunc func(*logrus.Entry) error
+
+func (h hookFunc) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h hookFunc) Fire(e *logrus.Entry) error {
+	return h(e)
+}
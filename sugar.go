@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// KVErrorsKey 无效的键值对，输出在ctx或extra中
+const KVErrorsKey = "_kv_errors"
+
+// SugaredLogger 以键值对形式记录日志，如Infow("paid", "order_id", id, "amount", amount)
+type SugaredLogger struct {
+	logger *logrus.Logger
+	fields logrus.Fields
+	buffer *RequestBuffer
+}
+
+// Sugar 创建键值对形式的日志对象，l通常由NewLogger创建
+func Sugar(l *logrus.Logger) *SugaredLogger {
+	return &SugaredLogger{logger: l}
+}
+
+// Logger 底层的logrus日志对象
+func (s *SugaredLogger) Logger() *logrus.Logger {
+	return s.logger
+}
+
+// With 返回附加了字段的日志对象，kvs为交替出现的key与value
+func (s *SugaredLogger) With(kvs ...interface{}) *SugaredLogger {
+	return &SugaredLogger{logger: s.logger, fields: kvFields(s.fields, kvs), buffer: s.buffer}
+}
+
+// WithContext 返回使用ctx中RequestBuffer的日志对象
+func (s *SugaredLogger) WithContext(ctx context.Context) *SugaredLogger {
+	return &SugaredLogger{logger: s.logger, fields: s.fields, buffer: RequestBufferFromContext(ctx)}
+}
+
+// Channel 返回输出到指定channel的日志对象，继承已附加的字段
+func (s *SugaredLogger) Channel(name string) *Channel {
+	c := newChannel(s.logger, name, s.fields)
+	c.buffer = s.buffer
+
+	return c
+}
+
+// Debugw 记录debug级别的日志
+func (s *SugaredLogger) Debugw(msg string, kvs ...interface{}) {
+	s.Logw(logrus.DebugLevel, msg, kvs...)
+}
+
+// Infow 记录info级别的日志
+func (s *SugaredLogger) Infow(msg string, kvs ...interface{}) {
+	s.Logw(logrus.InfoLevel, msg, kvs...)
+}
+
+// Warnw 记录warning级别的日志
+func (s *SugaredLogger) Warnw(msg string, kvs ...interface{}) {
+	s.Logw(logrus.WarnLevel, msg, kvs...)
+}
+
+// Errorw 记录error级别的日志
+func (s *SugaredLogger) Errorw(msg string, kvs ...interface{}) {
+	s.Logw(logrus.ErrorLevel, msg, kvs...)
+}
+
+// Logw 记录指定级别的日志，日志级别未开启且不被缓存时不处理kvs
+func (s *SugaredLogger) Logw(level logrus.Level, msg string, kvs ...interface{}) {
+	if !s.logger.IsLevelEnabled(level) && !s.buffer.holds(level) {
+		return
+	}
+
+	logw(s.logger, s.buffer, kvFields(s.fields, kvs), level, msg)
+}
+
+// logw 记录日志，b不为nil时不按级别过滤缓存详细级别的日志，并在记录error及更严重级别的日志之前输出缓存的日志
+func logw(l *logrus.Logger, b *RequestBuffer, fields logrus.Fields, level logrus.Level, msg string) {
+	if b.hold(l, fields, level, msg) {
+		return
+	}
+	if level <= logrus.ErrorLevel {
+		b.Flush()
+	}
+
+	entry := logrus.NewEntry(l)
+	entry.Data = fields
+	entry.Log(level, msg)
+}
+
+// kvFields 将键值对合并到fields的副本中，key不是字符串或缺少value的键值对不会被丢弃，
+// 而是记录在KVErrorsKey中，如[{"index": 2, "key": "1", "value": "x", "error": "key is not a string"}]
+func kvFields(fields logrus.Fields, kvs []interface{}) logrus.Fields {
+	out := make(logrus.Fields, len(fields)+len(kvs)/2)
+	for k, v := range fields {
+		out[k] = v
+	}
+
+	var errs []interface{}
+	for i := 0; i < len(kvs); i += 2 {
+		key, ok := kvs[i].(string)
+		switch {
+		case i+1 == len(kvs):
+			errs = append(errs, map[string]interface{}{
+				"index": i,
+				"key":   fmt.Sprint(kvs[i]),
+				"error": "missing value",
+			})
+		case !ok:
+			errs = append(errs, map[string]interface{}{
+				"index": i,
+				"key":   fmt.Sprint(kvs[i]),
+				"value": kvs[i+1],
+				"error": fmt.Sprintf("key is not a string: %T", kvs[i]),
+			})
+		default:
+			out[key] = kvs[i+1]
+		}
+	}
+	if len(errs) > 0 {
+		// 保留With中记录的无效键值对
+		if prev, ok := fields[KVErrorsKey].([]interface{}); ok {
+			errs = append(prev[:len(prev):len(prev)], errs...)
+		}
+		out[KVErrorsKey] = errs
+	}
+
+	return out
+}
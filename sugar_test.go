@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSugaredLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, _ := NewLogger(APPLogsV1, WithOutput(buf), WithLevel(logrus.InfoLevel), WithSortedKeys())
+	s := Sugar(l)
+	if s.Logger() != l {
+		t.Fatal("Sugar() logger, Expected=l, Actual=other")
+	}
+
+	s.Debugw("disabled", "k", "v")
+	if buf.Len() != 0 {
+		t.Fatalf("Debugw() output, Expected=%q, Actual=%q", "", buf.String())
+	}
+
+	payment := s.Channel("payment").With("order_id", 1001)
+	payment.Infow("paid", "amount", 1.5, "user", "u1")
+	data := buf.Bytes()
+	cases := []struct {
+		path     []interface{}
+		expected string
+	}{
+		{path: []interface{}{"channel"}, expected: "payment"},
+		{path: []interface{}{"level"}, expected: "info"},
+		{path: []interface{}{"msg"}, expected: "paid"},
+		{path: []interface{}{"ctx", "order_id"}, expected: "1001"},
+		{path: []interface{}{"ctx", "amount"}, expected: "1.5"},
+		{path: []interface{}{"ctx", "user"}, expected: "u1"},
+		{path: []interface{}{"ctx", KVErrorsKey}, expected: ""},
+	}
+	for _, c := range cases {
+		if actual := jsoniter.Get(data, c.path...).ToString(); actual != c.expected {
+			t.Fatalf("Infow() output %v, Expected=%q, Actual=%q", c.path, c.expected, actual)
+		}
+	}
+
+	// 无效的键值对
+	buf.Reset()
+	s.With(1, "a").Warnw("invalid", "ok", true, 2, "b", "dangling")
+	data = buf.Bytes()
+	cases = []struct {
+		path     []interface{}
+		expected string
+	}{
+		{path: []interface{}{"level"}, expected: "warning"},
+		{path: []interface{}{"ctx", "ok"}, expected: "true"},
+		{path: []interface{}{"ctx", KVErrorsKey, 0, "index"}, expected: "0"},
+		{path: []interface{}{"ctx", KVErrorsKey, 0, "key"}, expected: "1"},
+		{path: []interface{}{"ctx", KVErrorsKey, 0, "value"}, expected: "a"},
+		{path: []interface{}{"ctx", KVErrorsKey, 0, "error"}, expected: "key is not a string: int"},
+		{path: []interface{}{"ctx", KVErrorsKey, 1, "index"}, expected: "2"},
+		{path: []interface{}{"ctx", KVErrorsKey, 1, "value"}, expected: "b"},
+		{path: []interface{}{"ctx", KVErrorsKey, 2, "index"}, expected: "4"},
+		{path: []interface{}{"ctx", KVErrorsKey, 2, "key"}, expected: "dangling"},
+		{path: []interface{}{"ctx", KVErrorsKey, 2, "error"}, expected: "missing value"},
+	}
+	for _, c := range cases {
+		if actual := jsoniter.Get(data, c.path...).ToString(); actual != c.expected {
+			t.Fatalf("Warnw() output %v, Expected=%q, Actual=%q", c.path, c.expected, actual)
+		}
+	}
+}
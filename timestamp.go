@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInvalidEpochUnit Unix时间戳的精度不是秒、毫秒、微秒或纳秒
+	ErrInvalidEpochUnit = errors.New("invalid log epoch unit")
+)
+
+// timeEpoch 以json数字输出的Unix时间戳
+type timeEpoch struct {
+	// 字段名，为空时替换time字段输出
+	key   string
+	value int64
+	valid bool
+}
+
+// formatTime 按时区与时间格式格式化日志时间，unit大于0时同时计算对应精度的Unix时间戳
+func formatTime(t time.Time, loc *time.Location, layout string, unit time.Duration, epochKey string) (string, timeEpoch) {
+	if loc != nil {
+		t = t.In(loc)
+	}
+
+	epoch := timeEpoch{}
+	if validEpochUnit(unit) {
+		// 由秒与纳秒分别计算，避免UnixNano在1678年之前与2262年之后溢出
+		epoch = timeEpoch{
+			key:   epochKey,
+			value: t.Unix()*int64(time.Second/unit) + int64(t.Nanosecond())/int64(unit),
+			valid: true,
+		}
+	}
+
+	return t.Format(layout), epoch
+}
+
+// validEpochUnit Unix时间戳的精度是否为秒、毫秒、微秒或纳秒
+func validEpochUnit(unit time.Duration) bool {
+	switch unit {
+	case time.Second, time.Millisecond, time.Microsecond, time.Nanosecond:
+		return true
+	}
+	return false
+}
+
+// timeFields time字段及可选的Unix时间戳字段
+func timeFields(fs []field, t string, epoch timeEpoch) []field {
+	if !epoch.valid {
+		return append(fs, field{"time", t})
+	}
+	if epoch.key == "" {
+		return append(fs, field{"time", epoch.value})
+	}
+
+	return append(fs, field{"time", t}, field{epoch.key, epoch.value})
+}
@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func TestTimeOptions(t *testing.T) {
+	shanghai := time.FixedZone("Asia/Shanghai", 8*3600)
+	entry := &logrus.Entry{
+		Level:   logrus.InfoLevel,
+		Time:    time.Date(2019, 8, 12, 18, 13, 48, 123456789, shanghai),
+		Message: "hello",
+		Data:    logrus.Fields{},
+	}
+
+	cases := []struct {
+		opts     []Option
+		expected string
+	}{
+		{
+			opts:     []Option{WithTimeLocation(time.UTC)},
+			expected: `{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","msg":"hello"}`,
+		},
+		{
+			opts:     []Option{WithEpoch(time.Second)},
+			expected: `{"schema":"app.logs.v1","channel":"","level":"info","time":1565604828,"msg":"hello"}`,
+		},
+		{
+			opts:     []Option{WithEpoch(time.Millisecond)},
+			expected: `{"schema":"app.logs.v1","channel":"","level":"info","time":1565604828123,"msg":"hello"}`,
+		},
+		{
+			opts:     []Option{WithEpoch(time.Microsecond)},
+			expected: `{"schema":"app.logs.v1","channel":"","level":"info","time":1565604828123456,"msg":"hello"}`,
+		},
+		{
+			opts:     []Option{WithEpoch(time.Nanosecond)},
+			expected: `{"schema":"app.logs.v1","channel":"","level":"info","time":1565604828123456789,"msg":"hello"}`,
+		},
+		{
+			opts:     []Option{WithTimeLocation(time.UTC), WithEpochField("ts", time.Millisecond)},
+			expected: `{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","ts":1565604828123,"msg":"hello"}`,
+		},
+	}
+
+	for i, c := range cases {
+		f, err := NewFormatter(APPLogsV1, c.opts...)
+		if err != nil {
+			t.Fatalf("Test NewFormatter() case %d, Expected=nil, Actual=%q", i, err.Error())
+		}
+		data, err := f.Format(entry)
+		if err != nil {
+			t.Fatalf("Format() case %d error, Expected=nil, Actual=%q", i, err.Error())
+		}
+		if string(data) != c.expected+"\n" {
+			t.Fatalf("Format() case %d output, Expected=%q, Actual=%q", i, c.expected, data)
+		}
+	}
+
+	if _, err := NewFormatter(APPLogsV1, WithEpochField("msg", time.Second)); errors.Cause(err) != ErrInvalidKeyMap {
+		t.Fatalf("Test NewFormatter(), Expected=%q, Actual=%v", ErrInvalidKeyMap, err)
+	}
+	if _, err := NewFormatter(HTTPRequestV1, WithEpochField("ts", time.Second)); err != nil {
+		t.Fatalf("Test NewFormatter(), Expected=nil, Actual=%q", err.Error())
+	}
+	for _, unit := range []time.Duration{0, -time.Second, 10 * time.Millisecond, time.Minute} {
+		if _, err := NewFormatter(APPLogsV1, WithEpoch(unit)); errors.Cause(err) != ErrInvalidEpochUnit {
+			t.Fatalf("Test NewFormatter() unit %s, Expected=%q, Actual=%v", unit, ErrInvalidEpochUnit, err)
+		}
+		if _, err := NewFormatter(APPLogsV1, WithEpochField("ts", unit)); errors.Cause(err) != ErrInvalidEpochUnit {
+			t.Fatalf("Test NewFormatter() unit %s, Expected=%q, Actual=%v", unit, ErrInvalidEpochUnit, err)
+		}
+	}
+
+	// UnixNano可表示的范围之外的时间
+	f, err := NewFormatter(APPLogsV1, WithEpoch(time.Millisecond))
+	if err != nil {
+		t.Fatalf("Test NewFormatter(), Expected=nil, Actual=%q", err.Error())
+	}
+	for _, c := range []struct {
+		time     time.Time
+		expected int64
+	}{
+		{time: time.Date(2300, 1, 1, 0, 0, 0, 5e6, time.UTC), expected: 10413792000005},
+		{time: time.Date(1600, 1, 1, 0, 0, 0, 5e6, time.UTC), expected: -11676095999995},
+	} {
+		data, err := f.Format(&logrus.Entry{Time: c.time, Data: logrus.Fields{}})
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+		if actual := jsoniter.Get(data, "time").ToInt64(); actual != c.expected {
+			t.Fatalf("Format() time %s, Expected=%d, Actual=%d", c.time, c.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package logger
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// json类型，null不会引起mapping冲突，不记录也不添加后缀
+const (
+	jsonTypeString = "string"
+	jsonTypeNumber = "number"
+	jsonTypeBool   = "bool"
+	jsonTypeObject = "object"
+	jsonTypeArray  = "array"
+	jsonTypeNull   = "null"
+)
+
+// typeSuffixes TypeStable.Suffix为true时各json类型对应的key后缀
+var typeSuffixes = map[string]string{
+	jsonTypeString: "_s",
+	jsonTypeNumber: "_n",
+	jsonTypeBool:   "_b",
+	jsonTypeObject: "_o",
+	jsonTypeArray:  "_a",
+}
+
+// TypeStable 使ctx(http.request.v1为extra、get、post)中同名字段的类型保持稳定，
+// 避免Elasticsearch等存储因同一字段出现不同类型而产生mapping冲突
+type TypeStable struct {
+	// 按json类型为key添加后缀，字符串"_s"、数字"_n"、布尔"_b"、对象"_o"、数组"_a"，如"id_n"，
+	// 与原有key冲突时添加后缀的值再依次使用"_2"、"_3"...后缀
+	Suffix bool
+	// 将这些字段中的数字与布尔值转换为字符串，嵌套字段以"."连接，如"user.id"，Suffix为true时不生效
+	StringKeys []string
+	// 记录各字段出现过的json类型，为nil时不记录
+	Tracker *TypeTracker
+}
+
+func (ts TypeStable) enabled() bool {
+	return ts.Suffix || len(ts.StringKeys) > 0 || ts.Tracker != nil
+}
+
+// transform 是否需要修改日志内容
+func (ts TypeStable) transform() bool {
+	return ts.Suffix || len(ts.StringKeys) > 0
+}
+
+// apply 记录字段类型，并按需为key添加后缀或将标量转换为字符串
+func (ts TypeStable) apply(data logData) {
+	if !ts.enabled() {
+		return
+	}
+
+	switch d := data.(type) {
+	case *APPLogsV1Data:
+		d.Context = ts.stableMap("ctx", "", d.Context)
+	case *HTTPRequestV1Data:
+		d.Get = ts.stableMap("get", "", d.Get)
+		d.Post = ts.stableMap("post", "", d.Post)
+		d.Extra = ts.stableMap("extra", "", d.Extra)
+	}
+}
+
+// stableMap 只记录类型时返回m本身，否则返回新的map，不修改原有的map
+// container为ctx等容器字段名，path为m在容器中的路径
+func (ts TypeStable) stableMap(container, path string, m map[string]interface{}) map[string]interface{} {
+	if len(m) == 0 {
+		return m
+	}
+
+	var out map[string]interface{}
+	if ts.transform() {
+		out = make(map[string]interface{}, len(m))
+	}
+	var suffixed []field
+	for k, v := range m {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+		typ, v := ts.stableValue(container, p, v)
+		if out == nil {
+			continue
+		}
+
+		switch {
+		case ts.Suffix && typeSuffixes[typ] != "":
+			suffixed = append(suffixed, field{k + typeSuffixes[typ], v})
+			continue
+		case !ts.Suffix && ts.stringKey(p):
+			v = stringifyScalar(typ, v)
+		}
+		out[k] = v
+	}
+
+	if out == nil {
+		return m
+	}
+
+	// 加上后缀的key可能与原本就以后缀结尾的key(如null值的"id_n")冲突，
+	// 与flatten相同保留原有key的值，加上后缀的值依次使用"_2"、"_3"...后缀
+	sort.Slice(suffixed, func(i, j int) bool {
+		return suffixed[i].Key < suffixed[j].Key
+	})
+	for _, f := range suffixed {
+		key := f.Key
+		for n := 2; ; n++ {
+			if _, ok := out[key]; !ok {
+				break
+			}
+			key = f.Key + "_" + strconv.Itoa(n)
+		}
+		out[key] = f.Value
+	}
+
+	return out
+}
+
+// stableValue 记录值的类型，并递归处理对象与数组中的对象，数组中的对象与数组本身使用相同的路径
+// 返回值的json类型与处理后的值
+func (ts TypeStable) stableValue(container, path string, v interface{}) (string, interface{}) {
+	typ, plain := jsonType(v)
+	if typ == "" {
+		return typ, v
+	}
+	if ts.Tracker != nil && typ != jsonTypeNull {
+		ts.Tracker.record(container+"."+path, typ)
+	}
+
+	switch typ {
+	case jsonTypeObject:
+		// makeErrInfo生成的错误信息结构固定，不再处理
+		if _, _, ok := errInfo(v); ok {
+			return typ, v
+		}
+		m, _ := stringMap(plain)
+		if out := ts.stableMap(container, path, m); ts.transform() {
+			return typ, out
+		}
+	case jsonTypeArray:
+		rv := reflect.ValueOf(plain)
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		var out []interface{}
+		if ts.transform() {
+			out = make([]interface{}, rv.Len())
+		}
+		for i := 0; i < rv.Len(); i++ {
+			e := rv.Index(i).Interface()
+			if t, _ := jsonType(e); t == jsonTypeObject {
+				_, e = ts.stableValue(container, path, e)
+			}
+			if out != nil {
+				out[i] = e
+			}
+		}
+		if out != nil {
+			return typ, out
+		}
+	}
+
+	return typ, v
+}
+
+func (ts TypeStable) stringKey(path string) bool {
+	for _, k := range ts.StringKeys {
+		if k == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stringifyScalar 将数字与布尔值转换为json中的文本表示
+func stringifyScalar(typ string, v interface{}) interface{} {
+	if typ != jsonTypeNumber && typ != jsonTypeBool {
+		return v
+	}
+	b, err := jsoniter.ConfigDefault.Marshal(v)
+	if err != nil {
+		return v
+	}
+
+	return string(b)
+}
+
+// jsonType 返回值编码为json后的类型，以及可用于遍历对象与数组的值
+// 结构体等按json规则转换为基础类型，无法编码时类型为空
+func jsonType(v interface{}) (string, interface{}) {
+	switch v.(type) {
+	case nil:
+		return jsonTypeNull, v
+	case string:
+		return jsonTypeString, v
+	case bool:
+		return jsonTypeBool, v
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, jsoniter.Number:
+		return jsonTypeNumber, v
+	case []byte:
+		return jsonTypeString, v
+	}
+
+	if _, ok := stringMap(v); ok {
+		return jsonTypeObject, v
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Slice:
+		if rv.IsNil() {
+			return jsonTypeNull, v
+		}
+		return jsonTypeArray, v
+	case reflect.Array:
+		return jsonTypeArray, v
+	}
+
+	plain, err := toPlain(v)
+	if err != nil {
+		return "", v
+	}
+	return jsonType(plain)
+}
+
+// TypeTracker 记录ctx等容器中各字段出现过的json类型，用于发现mapping冲突，可并发使用，零值可直接使用
+type TypeTracker struct {
+	mu    sync.Mutex
+	types map[string]map[string]bool
+}
+
+// NewTypeTracker 创建TypeTracker，通过WithTypeStable添加到日志对象
+func NewTypeTracker() *TypeTracker {
+	return &TypeTracker{types: map[string]map[string]bool{}}
+}
+
+func (t *TypeTracker) record(path, typ string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.types == nil {
+		t.types = map[string]map[string]bool{}
+	}
+	types, ok := t.types[path]
+	if !ok {
+		types = map[string]bool{}
+		t.types[path] = types
+	}
+	types[typ] = true
+}
+
+// Conflicts 返回出现过多种json类型的字段及其类型，如{"ctx.id": ["number", "string"]}
+func (t *TypeTracker) Conflicts() map[string][]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	conflicts := map[string][]string{}
+	for path, types := range t.types {
+		if len(types) < 2 {
+			continue
+		}
+		list := make([]string, 0, len(types))
+		for typ := range types {
+			list = append(list, typ)
+		}
+		sort.Strings(list)
+		conflicts[path] = list
+	}
+
+	return conflicts
+}
@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestTypeStable(t *testing.T) {
+	now := time.Date(2019, 8, 12, 10, 13, 48, 0, time.UTC)
+	entry := func(data logrus.Fields) *logrus.Entry {
+		return &logrus.Entry{Level: logrus.InfoLevel, Time: now, Message: "hello", Data: data}
+	}
+
+	t.Run("Suffix", func(t *testing.T) {
+		f, _ := NewFormatter(APPLogsV1, WithSortedKeys(), WithTypeStable(TypeStable{Suffix: true}))
+		data, err := f.Format(entry(logrus.Fields{
+			"id":    1,
+			"name":  "a",
+			"ok":    true,
+			"none":  nil,
+			"user":  map[string]interface{}{"id": "u1"},
+			"items": []interface{}{map[string]interface{}{"n": 1}, "x"},
+			"at":    now,
+		}))
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		expected := `{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","msg":"hello",` +
+			`"ctx":{"at_s":"2019-08-12T10:13:48Z","id_n":1,"items_a":[{"n_n":1},"x"],"name_s":"a","none":null,"ok_b":true,` +
+			`"user_o":{"id_s":"u1"}}}` + "\n"
+		if string(data) != expected {
+			t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, data)
+		}
+	})
+
+	t.Run("SuffixCollision", func(t *testing.T) {
+		// null值不添加后缀，与加上后缀的key冲突时保留原有key
+		f, _ := NewFormatter(APPLogsV1, WithSortedKeys(), WithTypeStable(TypeStable{Suffix: true}))
+		data, err := f.Format(entry(logrus.Fields{
+			"id":     1,
+			"id_n":   nil,
+			"id_n_2": nil,
+			"name":   "a",
+			"name_s": "b",
+		}))
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		expected := `{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","msg":"hello",` +
+			`"ctx":{"id_n":null,"id_n_2":null,"id_n_3":1,"name_s":"a","name_s_s":"b"}}` + "\n"
+		if string(data) != expected {
+			t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, data)
+		}
+	})
+
+	t.Run("StringKeys", func(t *testing.T) {
+		f, _ := NewFormatter(APPLogsV1, WithSortedKeys(), WithTypeStable(TypeStable{StringKeys: []string{"id", "user.id", "ok"}}))
+		data, err := f.Format(entry(logrus.Fields{
+			"id":   1.5,
+			"ok":   false,
+			"n":    2,
+			"user": map[string]interface{}{"id": 3},
+		}))
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		expected := `{"schema":"app.logs.v1","channel":"","level":"info","time":"2019-08-12T10:13:48Z","msg":"hello",` +
+			`"ctx":{"id":"1.5","n":2,"ok":"false","user":{"id":"3"}}}` + "\n"
+		if string(data) != expected {
+			t.Fatalf("Format() output, Expected=%q, Actual=%q", expected, data)
+		}
+	})
+
+	t.Run("Tracker", func(t *testing.T) {
+		tracker := NewTypeTracker()
+		af, _ := NewFormatter(APPLogsV1, WithTypeStable(TypeStable{Tracker: tracker}))
+		hf, _ := NewFormatter(HTTPRequestV1, WithTypeStable(TypeStable{Tracker: tracker}))
+
+		ctx := map[string]interface{}{"id": 1}
+		for _, e := range []*logrus.Entry{
+			entry(logrus.Fields{"id": 1, "user": ctx}),
+			entry(logrus.Fields{"id": "1", "user": map[string]interface{}{"id": true}}),
+			entry(logrus.Fields{"id": nil, "name": "a"}),
+		} {
+			if _, err := af.Format(e); err != nil {
+				t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+			}
+			if _, err := hf.Format(entry(logrus.Fields{
+				HTTPRequestReqKey: &http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/", RawQuery: "a=1&a=2&b=1"}},
+			})); err != nil {
+				t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+			}
+		}
+		if _, err := hf.Format(entry(logrus.Fields{
+			HTTPRequestReqKey: &http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/", RawQuery: "a=1"}},
+		})); err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+
+		expected := map[string][]string{
+			"ctx.id":      {"number", "string"},
+			"ctx.user.id": {"bool", "number"},
+			"get.a":       {"array", "string"},
+		}
+		if actual := tracker.Conflicts(); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("TypeTracker.Conflicts(), Expected=%v, Actual=%v", expected, actual)
+		}
+
+		// 只记录类型时不修改日志内容
+		if _, ok := ctx["id"].(int); !ok {
+			t.Fatal("Format() modified original ctx value")
+		}
+
+		// 零值的TypeTracker可直接使用
+		zero := &TypeTracker{}
+		zf, _ := NewFormatter(APPLogsV1, WithTypeStable(TypeStable{Tracker: zero}))
+		for _, id := range []interface{}{1, "1"} {
+			if _, err := zf.Format(entry(logrus.Fields{"id": id})); err != nil {
+				t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+			}
+		}
+		if actual := zero.Conflicts(); !reflect.DeepEqual(actual, map[string][]string{"ctx.id": {"number", "string"}}) {
+			t.Fatalf("TypeTracker.Conflicts() of zero value, Expected=%v, Actual=%v", map[string][]string{"ctx.id": {"number", "string"}}, actual)
+		}
+	})
+}
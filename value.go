@@ -0,0 +1,186 @@
+package logger
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ValueEncoder 将ctx、extra中特定类型的值转换为输出的值，返回值应可被json编码
+type ValueEncoder func(v interface{}) interface{}
+
+var (
+	errorType         = reflect.TypeOf((*error)(nil)).Elem()
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// valueEncoding ctx、extra中的值的转换方式，encoders中注册的类型优先于内置的转换方式:
+// LazyValue转换为计算结果，error转换为makeErrInfo生成的错误信息，time.Duration输出为"1.5s"，
+// time.Time输出为RFC3339Nano(location不为nil时转换时区)，net.IP输出为"127.0.0.1"，
+// UTF-8编码的[]byte输出为字符串，实现了encoding.TextMarshaler或fmt.Stringer的值输出为字符串，
+// 实现了json.Marshaler的值保持原样；map与slice中的值会被递归地转换
+type valueEncoding struct {
+	encoders map[reflect.Type]ValueEncoder
+	location *time.Location
+}
+
+// encodeFields 转换m中的值，m为提取日志内容时新建的map，可直接修改
+func (ve valueEncoding) encodeFields(m map[string]interface{}) {
+	for k, v := range m {
+		if out, ok := ve.encode(v); ok {
+			m[k] = out
+		}
+	}
+}
+
+// encode 返回转换后的值以及是否发生了转换，不修改原有的map与slice
+func (ve valueEncoding) encode(v interface{}) (interface{}, bool) {
+	switch v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v, false
+	}
+
+	rv := reflect.ValueOf(v)
+	if enc, ok := ve.encoders[rv.Type()]; ok {
+		return enc(v), true
+	}
+	// 值为nil的指针调用方法可能panic
+	if rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return v, false
+	}
+
+	switch x := v.(type) {
+	case LazyValue:
+		return ve.lazyValue(x), true
+	case error:
+		return makeErrInfo(x), true
+	case time.Duration:
+		return x.String(), true
+	case time.Time:
+		if ve.location != nil {
+			x = x.In(ve.location)
+		}
+		return x.Format(time.RFC3339Nano), true
+	case net.IP:
+		return x.String(), true
+	case []byte:
+		if utf8.Valid(x) {
+			return string(x), true
+		}
+		return v, false
+	case logrus.Fields:
+		if out, ok := ve.encodeMap(x); ok {
+			return logrus.Fields(out), true
+		}
+		return v, false
+	case map[string]interface{}:
+		if out, ok := ve.encodeMap(x); ok {
+			return out, true
+		}
+		return v, false
+	case []interface{}:
+		if out, ok := ve.encodeSlice(x); ok {
+			return out, true
+		}
+		return v, false
+	case json.Marshaler:
+		return v, false
+	case encoding.TextMarshaler:
+		b, err := x.MarshalText()
+		if err != nil {
+			return v, false
+		}
+		return string(b), true
+	case fmt.Stringer:
+		return x.String(), true
+	}
+
+	return ve.encodeReflect(rv)
+}
+
+// encodeMap 有值发生转换时返回新的map
+func (ve valueEncoding) encodeMap(m map[string]interface{}) (map[string]interface{}, bool) {
+	var out map[string]interface{}
+	for k, v := range m {
+		e, ok := ve.encode(v)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(m))
+			for k, v := range m {
+				out[k] = v
+			}
+		}
+		out[k] = e
+	}
+
+	return out, out != nil
+}
+
+// encodeSlice 有值发生转换时返回新的slice
+func (ve valueEncoding) encodeSlice(s []interface{}) ([]interface{}, bool) {
+	var out []interface{}
+	for i, v := range s {
+		e, ok := ve.encode(v)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = append([]interface{}(nil), s...)
+		}
+		out[i] = e
+	}
+
+	return out, out != nil
+}
+
+// encodeReflect 转换元素类型可能需要转换的slice、数组与key为字符串的map
+func (ve valueEncoding) encodeReflect(rv reflect.Value) (interface{}, bool) {
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if !ve.mayEncode(rv.Type().Elem()) {
+			break
+		}
+		s := make([]interface{}, rv.Len())
+		for i := range s {
+			s[i] = rv.Index(i).Interface()
+		}
+		if out, ok := ve.encodeSlice(s); ok {
+			return out, true
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String || !ve.mayEncode(rv.Type().Elem()) {
+			break
+		}
+		m := make(map[string]interface{}, rv.Len())
+		for _, k := range rv.MapKeys() {
+			m[k.String()] = rv.MapIndex(k).Interface()
+		}
+		if out, ok := ve.encodeMap(m); ok {
+			return out, true
+		}
+	}
+
+	return rv.Interface(), false
+}
+
+// mayEncode 该类型的值是否可能需要转换
+func (ve valueEncoding) mayEncode(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface, reflect.Ptr, reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	if _, ok := ve.encoders[t]; ok {
+		return true
+	}
+
+	return t.Implements(errorType) || t.Implements(textMarshalerType) || t.Implements(stringerType)
+}
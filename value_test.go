@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+type testMoney struct {
+	Cents    int64
+	Currency string
+}
+
+type testLevel int
+
+func (l testLevel) String() string {
+	return [...]string{"low", "high"}[l]
+}
+
+func TestFormatterValueEncoding(t *testing.T) {
+	now := time.Date(2019, 8, 12, 10, 13, 48, 500, time.UTC)
+	nested := map[string]interface{}{"err": errors.New("nested"), "wait": 2 * time.Second}
+
+	f, _ := NewFormatter(APPLogsV1,
+		WithTimeLocation(time.FixedZone("CST", 8*3600)),
+		WithValueEncoder(testMoney{}, func(v interface{}) interface{} {
+			m := v.(testMoney)
+			return fmt.Sprintf("%d.%02d %s", m.Cents/100, m.Cents%100, m.Currency)
+		}),
+	)
+	data, err := f.Format(&logrus.Entry{
+		Level:   logrus.InfoLevel,
+		Time:    now,
+		Message: "hello",
+		Data: logrus.Fields{
+			"duration": 1500 * time.Millisecond,
+			"at":       now,
+			"text":     []byte("raw"),
+			"binary":   []byte{0xff, 0xfe},
+			"ip":       net.ParseIP("127.0.0.1"),
+			"level":    testLevel(1),
+			"levels":   []testLevel{0, 1},
+			"price":    testMoney{Cents: 150, Currency: "CNY"},
+			"nested":   nested,
+			"list":     []interface{}{errors.New("in list"), 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+	}
+
+	cases := []struct {
+		path     []interface{}
+		expected string
+	}{
+		{path: []interface{}{"ctx", "duration"}, expected: "1.5s"},
+		{path: []interface{}{"ctx", "at"}, expected: "2019-08-12T18:13:48.0000005+08:00"},
+		{path: []interface{}{"ctx", "text"}, expected: "raw"},
+		{path: []interface{}{"ctx", "binary"}, expected: "//4="},
+		{path: []interface{}{"ctx", "ip"}, expected: "127.0.0.1"},
+		{path: []interface{}{"ctx", "level"}, expected: "high"},
+		{path: []interface{}{"ctx", "levels", 0}, expected: "low"},
+		{path: []interface{}{"ctx", "price"}, expected: "1.50 CNY"},
+		{path: []interface{}{"ctx", "nested", "err", "msg"}, expected: "nested"},
+		{path: []interface{}{"ctx", "nested", "wait"}, expected: "2s"},
+		{path: []interface{}{"ctx", "list", 0, "msg"}, expected: "in list"},
+		{path: []interface{}{"ctx", "list", 1}, expected: "1"},
+	}
+	for _, c := range cases {
+		if actual := jsoniter.Get(data, c.path...).ToString(); actual != c.expected {
+			t.Fatalf("Format() output %v, Expected=%q, Actual=%q", c.path, c.expected, actual)
+		}
+	}
+
+	// 原有的值不应被修改
+	if _, ok := nested["err"].(error); !ok {
+		t.Fatal("Format() modified original ctx value")
+	}
+}
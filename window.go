@@ -0,0 +1,168 @@
+package logger
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// windowCounter 按窗口计数的计数器
+type windowCounter interface {
+	// started 窗口开始的时间
+	started() time.Time
+	// summary 窗口结束时输出的汇总日志，不需要输出时返回nil
+	summary(window time.Duration) *logrus.Entry
+}
+
+// windowCounters 按key分别计数，窗口结束后输出汇总日志的计数器集合，可并发使用，零值可直接使用
+type windowCounters struct {
+	mu       sync.Mutex
+	counters map[interface{}]windowCounter
+	swept    time.Time
+}
+
+// update 更新key对应的计数器，计数器的窗口已结束时先删除并输出汇总日志，
+// fn的参数为nil时需要返回新的计数器；每个窗口清理一次其他已结束的计数器，返回需要输出的汇总日志
+func (w *windowCounters) update(key interface{}, now time.Time, window time.Duration, fn func(c windowCounter) windowCounter) []*logrus.Entry {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.counters == nil {
+		w.counters = make(map[interface{}]windowCounter)
+	}
+	var summaries []*logrus.Entry
+	if now.Sub(w.swept) >= window {
+		w.swept = now
+		summaries = w.expire(now, window, false)
+	}
+	c := w.counters[key]
+	if c != nil && now.Sub(c.started()) >= window {
+		if s := c.summary(window); s != nil {
+			summaries = append(summaries, s)
+		}
+		c = nil
+	}
+	w.counters[key] = fn(c)
+	sortSummaries(summaries)
+
+	return summaries
+}
+
+// expire 删除now之前窗口已结束的计数器，all为true时删除全部计数器，返回需要输出的汇总日志，调用方需持有锁
+func (w *windowCounters) expire(now time.Time, window time.Duration, all bool) []*logrus.Entry {
+	var summaries []*logrus.Entry
+	for key, c := range w.counters {
+		if all || now.Sub(c.started()) >= window {
+			if s := c.summary(window); s != nil {
+				summaries = append(summaries, s)
+			}
+			delete(w.counters, key)
+		}
+	}
+
+	return summaries
+}
+
+// flush 将now之前结束的窗口的汇总日志经f格式化后写入out，all为true时输出全部计数器的汇总日志
+func (w *windowCounters) flush(f logrus.Formatter, out io.Writer, now time.Time, window time.Duration, all bool) error {
+	w.mu.Lock()
+	summaries := w.expire(now, window, all)
+	w.mu.Unlock()
+	sortSummaries(summaries)
+
+	if output := formatSummaries(f, summaries); len(output) > 0 {
+		_, err := out.Write(output)
+		return err
+	}
+	return nil
+}
+
+// sortSummaries 汇总日志按时间与msg排序
+func sortSummaries(summaries []*logrus.Entry) {
+	sort.Slice(summaries, func(i, j int) bool {
+		if !summaries[i].Time.Equal(summaries[j].Time) {
+			return summaries[i].Time.Before(summaries[j].Time)
+		}
+		return summaries[i].Message < summaries[j].Message
+	})
+}
+
+// formatSummaries 格式化汇总日志，格式化失败(如http.request.v1日志缺少request)的汇总日志被丢弃
+func formatSummaries(f logrus.Formatter, summaries []*logrus.Entry) []byte {
+	var output []byte
+	for _, s := range summaries {
+		if b, err := f.Format(s); err == nil {
+			output = append(output, b...)
+		}
+	}
+
+	return output
+}
+
+// summaryFlusher 可以输出汇总日志的格式化对象
+type summaryFlusher interface {
+	flushSummaries(w io.Writer, now time.Time, all bool) error
+}
+
+// FlushSummaries 每隔interval(小于等于0时为1秒)将l中SamplingFormatter、DedupeFormatter已结束窗口的汇总日志写入l.Out，
+// 避免之后没有日志时汇总日志一直不输出；返回的stop停止定时输出并输出全部剩余的汇总日志，程序退出前应调用。
+// 汇总日志不经过logrus直接写入l.Out，不会触发hook，l.Out需要支持并发写入(如os.Stdout)
+func FlushSummaries(l *logrus.Logger, interval time.Duration) (stop func()) {
+	flush := func(all bool) {
+		for f := l.Formatter; f != nil; f = innerFormatter(f) {
+			if sf, ok := f.(summaryFlusher); ok {
+				_ = sf.flushSummaries(l.Out, time.Now(), all)
+			}
+		}
+	}
+
+	if interval <= 0 {
+		interval = time.Second
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				flush(false)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+			flush(true)
+		})
+	}
+}
+
+// innerFormatter 包装类格式化对象包装的格式化对象，其他格式化对象返回nil
+func innerFormatter(f logrus.Formatter) logrus.Formatter {
+	switch f := f.(type) {
+	case *QuotaFormatter:
+		return f.Formatter
+	case *SamplingFormatter:
+		return f.Formatter
+	case *DedupeFormatter:
+		return f.Formatter
+	case *RequestSamplingFormatter:
+		return f.Formatter
+	case *SlowRequestFormatter:
+		return f.Formatter
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+// formatLines 返回用f格式化日志并将输出的各行追加到output的函数
+func formatLines(t *testing.T, f logrus.Formatter, output *[]string) func(entry *logrus.Entry) {
+	return func(entry *logrus.Entry) {
+		data, err := f.Format(entry)
+		if err != nil {
+			t.Fatalf("Format() error, Expected=nil, Actual=%q", err.Error())
+		}
+		if len(data) > 0 {
+			*output = append(*output, strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")...)
+		}
+	}
+}
+
+// loggerLines 通过NewLogger创建日志对象，记录3条相同的日志，返回日志对象、输出与输出的行数
+func loggerLines(t *testing.T, opt Option) (*logrus.Logger, *bytes.Buffer, int) {
+	buf := &bytes.Buffer{}
+	l, err := NewLogger(APPLogsV1, WithOutput(buf), opt)
+	if err != nil {
+		t.Fatalf("NewLogger() error, Expected=nil, Actual=%q", err.Error())
+	}
+	for i := 0; i < 3; i++ {
+		l.WithError(errors.New("timeout")).Error("call failed")
+	}
+
+	return l, buf, strings.Count(buf.String(), "\n")
+}
+
+// lockedBuffer 可并发写入的bytes.Buffer
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+func TestFlushSummaries(t *testing.T) {
+	cases := []struct {
+		name string
+		opt  Option
+		key  string
+	}{
+		{name: "Sampling", opt: WithSampling(Sampling{Interval: time.Hour, First: 1}), key: SampledCountKey},
+		{name: "Dedupe", opt: WithDedupe(Dedupe{Window: time.Hour}), key: RepeatedCountKey},
+	}
+
+	for _, c := range cases {
+		// 之后没有日志时汇总日志在stop时输出
+		l, buf, _ := loggerLines(t, c.opt)
+		buf.Reset()
+		stop := FlushSummaries(l, time.Hour)
+		stop()
+		if actual := jsoniter.Get(buf.Bytes(), "ctx", c.key).ToInt(); actual != 2 {
+			t.Fatalf("FlushSummaries() %s %s, Expected=2, Actual=%d (%q)", c.name, c.key, actual, buf.String())
+		}
+	}
+
+	// 定时输出已结束窗口的汇总日志
+	l, _, _ := loggerLines(t, WithDedupe(Dedupe{Window: 50 * time.Millisecond}))
+	out := &lockedBuffer{}
+	l.SetOutput(out)
+	time.Sleep(60 * time.Millisecond)
+	stop := FlushSummaries(l, time.Millisecond)
+	defer stop()
+	for i := 0; i < 100 && len(out.Bytes()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if actual := jsoniter.Get(out.Bytes(), "ctx", RepeatedCountKey).ToInt(); actual != 2 {
+		t.Fatalf("FlushSummaries() %s, Expected=2, Actual=%d (%q)", RepeatedCountKey, actual, out.Bytes())
+	}
+}